@@ -12,6 +12,7 @@ import (
 	"bytes"
 	"errors"
 	"github.com/geziyor/geziyor/cache/memorycache"
+	"github.com/geziyor/geziyor/client"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -58,7 +59,19 @@ type Cache interface {
 	Delete(key string)
 }
 
-// cacheKey returns the cache key for req.
+// cacheKey returns the cache key for req, which is request fingerprint if Fingerprinter is set.
+// URL is used otherwise, or if request can't be fingerprinted.
+func (t *Transport) cacheKey(req *http.Request) string {
+	if t.Fingerprinter == nil {
+		return cacheKey(req)
+	}
+	if fingerprint, err := t.Fingerprinter.Fingerprint(req); err == nil {
+		return fingerprint
+	}
+	return cacheKey(req)
+}
+
+// cacheKey returns the URL based cache key for req.
 func cacheKey(req *http.Request) string {
 	if req.Method == http.MethodGet {
 		return req.URL.String()
@@ -70,7 +83,11 @@ func cacheKey(req *http.Request) string {
 // CachedResponse returns the cached http.Response for req if present, and nil
 // otherwise.
 func CachedResponse(c Cache, req *http.Request) (resp *http.Response, err error) {
-	cachedVal, ok := c.Get(cacheKey(req))
+	return cachedResponse(c, req, (&Transport{}).cacheKey(req))
+}
+
+func cachedResponse(c Cache, req *http.Request, key string) (resp *http.Response, err error) {
+	cachedVal, ok := c.Get(key)
 	if !ok {
 		return
 	}
@@ -90,6 +107,8 @@ type Transport struct {
 	Cache     Cache
 	// If true, responses returned from the cache will be given an extra header, X-From-Cache
 	MarkCachedResponses bool
+	// Fingerprinter used for cache keys. If nil, URLs are used as keys, so that existing caches are kept.
+	Fingerprinter client.RequestFingerprinter
 }
 
 // NewTransport returns a new Transport with the
@@ -134,11 +153,11 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 // Every request and its corresponding response are cached.
 // When the same request is seen again, the response is returned without transferring anything from the Internet.
 func (t *Transport) RoundTripDummy(req *http.Request) (resp *http.Response, err error) {
-	cacheKey := cacheKey(req)
+	cacheKey := t.cacheKey(req)
 	cacheable := (req.Method == "GET" || req.Method == "HEAD") && req.Header.Get("range") == ""
 	var cachedResp *http.Response
 	if cacheable {
-		cachedResp, err = CachedResponse(t.Cache, req)
+		cachedResp, err = cachedResponse(t.Cache, req, cacheKey)
 	} else {
 		// Need to invalidate an existing value
 		t.Cache.Delete(cacheKey)
@@ -180,11 +199,11 @@ func (t *Transport) RoundTripDummy(req *http.Request) (resp *http.Response, err
 // to give the server a chance to respond with NotModified. If this happens, then the cached Response
 // will be returned.
 func (t *Transport) RoundTripRFC2616(req *http.Request) (resp *http.Response, err error) {
-	cacheKey := cacheKey(req)
+	cacheKey := t.cacheKey(req)
 	cacheable := (req.Method == "GET" || req.Method == "HEAD") && req.Header.Get("range") == ""
 	var cachedResp *http.Response
 	if cacheable {
-		cachedResp, err = CachedResponse(t.Cache, req)
+		cachedResp, err = cachedResponse(t.Cache, req, cacheKey)
 	} else {
 		// Need to invalidate an existing value
 		t.Cache.Delete(cacheKey)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"flag"
 	"github.com/geziyor/geziyor/cache/memorycache"
+	"github.com/geziyor/geziyor/client"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -230,6 +231,49 @@ func TestCacheableMethod(t *testing.T) {
 	}
 }
 
+func TestFingerprinterCacheKey(t *testing.T) {
+	resetTest()
+	s.transport.Fingerprinter = client.DefaultFingerprinter
+	defer func() { s.transport.Fingerprinter = nil }()
+
+	for i, path := range []string{"/method?b=2&a=1", "/method?a=1&b=2&utm_source=x"} {
+		req, err := http.NewRequest("GET", s.server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got, want := resp.Header.Get(XFromCache) == "1", i == 1; got != want {
+			t.Errorf("%s: got cached %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestDefaultCacheKeyIsURL(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://example.com/?b=2&a=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := (&Transport{}).cacheKey(req); key != req.URL.String() {
+		t.Errorf("cache key %q isn't URL %q", key, req.URL.String())
+	}
+
+	fingerprint, err := client.DefaultFingerprinter.Fingerprint(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := (&Transport{Fingerprinter: client.DefaultFingerprinter}).cacheKey(req); key != fingerprint {
+		t.Errorf("cache key %q isn't fingerprint %q", key, fingerprint)
+	}
+}
+
 func TestDontServeHeadResponseToGetRequest(t *testing.T) {
 	resetTest()
 	url := s.server.URL + "/"
@@ -8,12 +8,15 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/chromedp/cdproto/dom"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	"github.com/geziyor/geziyor/internal"
+	"github.com/geziyor/geziyor/metrics"
 	"golang.org/x/net/html/charset"
 	"golang.org/x/text/transform"
 )
@@ -26,18 +29,22 @@ var (
 // Client is a small wrapper around *http.Client to provide new methods.
 type Client struct {
 	*http.Client
-	opt *Options
+	opt      *Options
+	sessions sync.Map
 }
 
 // Options is custom http.client options
 type Options struct {
 	MaxBodySize           int64
+	MaxBodyAction         MaxBodyAction
 	CharsetDetectDisabled bool
 	RetryTimes            int
 	RetryHTTPCodes        []int
 	RemoteAllocatorURL    string
 	AllocatorOptions      []chromedp.ExecAllocatorOption
 	ProxyFunc             func(*http.Request) (*url.URL, error)
+	// ProxyPool overrides ProxyFunc and tracks health of the proxies using responses.
+	ProxyPool *ProxyPool
 	// Changing this will override the existing default PreActions for Rendered requests.
 	// Geziyor Response will be nearly empty. Because we have no way to extract response without default pre actions.
 	// So, if you set this, you should handle all navigation, header setting, and response handling yourself.
@@ -47,9 +54,10 @@ type Options struct {
 
 // Default values for client
 const (
-	DefaultUserAgent        = "Geziyor 1.0"
-	DefaultMaxBody    int64 = 1024 * 1024 * 1024 // 1GB
-	DefaultRetryTimes       = 2
+	DefaultUserAgent         = "Geziyor 1.0"
+	DefaultMaxBody     int64 = 1024 * 1024 * 1024 // 1GB
+	DefaultRetryTimes        = 2
+	DefaultMaxRedirect       = 10
 )
 
 var (
@@ -63,10 +71,16 @@ func NewClient(opt *Options) *Client {
 	if opt.ProxyFunc != nil {
 		proxyFunction = opt.ProxyFunc
 	}
+	if opt.ProxyPool != nil {
+		if opt.ProxyPool.Metrics == nil {
+			opt.ProxyPool.Metrics = metrics.NewMetrics(metrics.Discard)
+		}
+		proxyFunction = opt.ProxyPool.GetProxy
+	}
 
 	httpClient := &http.Client{
 		Transport: &http.Transport{
-			Proxy: proxyFunction,
+			Proxy: sessionProxy(proxyFunction),
 			DialContext: (&net.Dialer{
 				Timeout:   30 * time.Second,
 				KeepAlive: 30 * time.Second,
@@ -90,7 +104,9 @@ func NewClient(opt *Options) *Client {
 	return &client
 }
 
-// DoRequest selects appropriate request handler, client or Chrome
+// DoRequest selects appropriate request handler, client or Chrome.
+// Session headers aren't set here, so that they take precedence over default headers of middlewares;
+// call SetSessionHeaders before if client is used directly.
 func (c *Client) DoRequest(req *Request) (resp *Response, err error) {
 	if req.Rendered {
 		resp, err = c.doRequestChrome(req)
@@ -98,13 +114,20 @@ func (c *Client) DoRequest(req *Request) (resp *Response, err error) {
 		resp, err = c.doRequestClient(req)
 	}
 
-	// Retry on Error
+	// Retry on Error. Too large bodies and unknown sessions won't change on retries.
 	if err != nil {
-		if req.retryCounter < c.opt.RetryTimes {
+		var bodyTooLargeErr *BodyTooLargeError
+		if errors.Is(err, ErrSessionNotFound) {
+			return resp, err
+		}
+		if req.retryCounter < c.opt.RetryTimes && !errors.As(err, &bodyTooLargeErr) {
 			req.retryCounter++
 			internal.Logger.Println("Retrying:", req.URL.String())
 			return c.DoRequest(req)
 		}
+		if req.retryCounter != 0 {
+			err = &RetriesExhaustedError{Retries: req.retryCounter, Err: err}
+		}
 		return resp, err
 	}
 
@@ -122,19 +145,53 @@ func (c *Client) DoRequest(req *Request) (resp *Response, err error) {
 
 // doRequestClient is a simple wrapper to read response according to options.
 func (c *Client) doRequestClient(req *Request) (*Response, error) {
+	httpClient, httpRequest, err := c.sessionClient(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.RedirectCount != 0 {
+		httpRequest = httpRequest.WithContext(context.WithValue(httpRequest.Context(), redirectCountKey(0), req.RedirectCount))
+	}
+	if req.RedirectsDisabled {
+		noRedirectClient := *httpClient
+		noRedirectClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		httpClient = &noRedirectClient
+	}
+
+	// Track proxy used for request
+	var trace *proxyTrace
+	if c.opt.ProxyPool != nil {
+		httpRequest, trace = c.opt.ProxyPool.withTrace(httpRequest)
+	}
+	start := time.Now()
+
 	// Do request
-	resp, err := c.Do(req.Request)
+	resp, err := httpClient.Do(httpRequest)
 	defer func() {
 		if resp != nil {
 			resp.Body.Close()
 		}
 	}()
 	if err != nil {
-		return nil, fmt.Errorf("response: %w", err)
+		if trace != nil {
+			c.opt.ProxyPool.observe(trace, 0, nil, nil, err, time.Since(start))
+		}
+		return nil, fmt.Errorf("response: %w", classifyError(err))
 	}
 
 	// Limit response body reading
-	bodyReader := io.LimitReader(resp.Body, c.opt.MaxBodySize)
+	maxBodySize := c.opt.MaxBodySize
+	if req.MaxBodySize != 0 {
+		maxBodySize = req.MaxBodySize
+	}
+	abort := c.opt.MaxBodyAction == MaxBodyAbort
+	if abort && resp.Request.Method != "HEAD" && resp.ContentLength > maxBodySize {
+		return nil, &BodyTooLargeError{MaxBodySize: maxBodySize}
+	}
+	limitReader := &maxBodyReader{r: resp.Body, n: maxBodySize, abort: abort, max: maxBodySize}
+	var bodyReader io.Reader = limitReader
 
 	// Decode response
 	if resp.Request.Method != "HEAD" && resp.ContentLength > 0 {
@@ -147,21 +204,29 @@ func (c *Client) doRequestClient(req *Request) (*Response, error) {
 				contentType := req.Header.Get("Content-Type")
 				bodyReader, err = charset.NewReader(bodyReader, contentType)
 				if err != nil {
-					return nil, fmt.Errorf("charset detection error on content-type %s: %w", contentType, err)
+					return nil, &CharsetError{ContentType: contentType, Err: err}
 				}
 			}
 		}
 	}
 
 	body, err := io.ReadAll(bodyReader)
+	if trace != nil {
+		c.opt.ProxyPool.observe(trace, resp.StatusCode, resp.Header, body, err, time.Since(start))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("reading body: %w", err)
 	}
+	if limitReader.exceeded && c.opt.MaxBodyAction == MaxBodyWarn {
+		internal.Logger.Printf("Response body truncated to %d bytes: %s\n", maxBodySize, req.URL)
+	}
 
 	response := Response{
-		Response: resp,
-		Body:     body,
-		Request:  req,
+		Response:  resp,
+		Body:      body,
+		Request:   req,
+		Redirects: redirectHistory(resp),
+		Truncated: limitReader.exceeded,
 	}
 
 	return &response, nil
@@ -169,13 +234,28 @@ func (c *Client) doRequestClient(req *Request) (*Response, error) {
 
 // doRequestChrome opens up a new chrome instance and makes request
 func (c *Client) doRequestChrome(req *Request) (*Response, error) {
+	var session *Session
+	if req.SessionID != "" {
+		var ok bool
+		if session, ok = c.Session(req.SessionID); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, req.SessionID)
+		}
+	}
+
 	// Set remote allocator or use local chrome instance
 	var allocCtx context.Context
 	var allocCancel context.CancelFunc
 	if c.opt.RemoteAllocatorURL != "" {
+		if session != nil && session.Proxy != nil {
+			return nil, fmt.Errorf("session proxy is not supported with remote browser: %s", req.SessionID)
+		}
 		allocCtx, allocCancel = chromedp.NewRemoteAllocator(context.Background(), c.opt.RemoteAllocatorURL)
 	} else {
-		allocCtx, allocCancel = chromedp.NewExecAllocator(context.Background(), c.opt.AllocatorOptions...)
+		allocatorOptions := c.opt.AllocatorOptions
+		if session != nil {
+			allocatorOptions = sessionAllocatorOptions(session, allocatorOptions)
+		}
+		allocCtx, allocCancel = chromedp.NewExecAllocator(context.Background(), allocatorOptions...)
 	}
 	defer allocCancel()
 
@@ -186,6 +266,9 @@ func (c *Client) doRequestChrome(req *Request) (*Response, error) {
 	// Initiate default pre actions
 	var body string
 	var res *network.Response
+	var redirects []Redirect
+	var redirectsMut sync.Mutex
+	var documentRequestID network.RequestID
 	var defaultPreActions = []chromedp.Action{
 		network.Enable(),
 		network.SetExtraHTTPHeaders(ConvertHeaderToMap(req.Header)),
@@ -196,6 +279,22 @@ func (c *Client) doRequestChrome(req *Request) (*Response, error) {
 						res = event.Response
 					}
 				}
+				// Redirects of the first document request are sent with the same request id
+				if event, ok := ev.(*network.EventRequestWillBeSent); ok && event.Type == "Document" {
+					redirectsMut.Lock()
+					defer redirectsMut.Unlock()
+					if documentRequestID == "" {
+						documentRequestID = event.RequestID
+					}
+					if event.RequestID == documentRequestID && event.RedirectResponse != nil {
+						redirectURL, _ := url.Parse(event.RedirectResponse.URL)
+						redirects = append(redirects, Redirect{
+							URL:        redirectURL,
+							StatusCode: int(event.RedirectResponse.Status),
+							Header:     ConvertMapToHeader(event.RedirectResponse.Headers),
+						})
+					}
+				}
 			})
 			return nil
 		}),
@@ -219,9 +318,15 @@ func (c *Client) doRequestChrome(req *Request) (*Response, error) {
 	// Append custom actions to default ones.
 	defaultPreActions = append(defaultPreActions, req.Actions...)
 
+	// Share cookies between session and browser
+	if session != nil {
+		before, after := sessionBrowserActions(session, req)
+		defaultPreActions = append(append(before, defaultPreActions...), after...)
+	}
+
 	// Run all actions
 	if err := chromedp.Run(taskCtx, defaultPreActions...); err != nil {
-		return nil, fmt.Errorf("request getting rendered: %w", err)
+		return nil, &RenderError{Err: err}
 	}
 
 	httpResponse := &http.Response{
@@ -237,10 +342,13 @@ func (c *Client) doRequestChrome(req *Request) (*Response, error) {
 		httpResponse.Header = ConvertMapToHeader(res.Headers)
 	}
 
+	redirectsMut.Lock()
+	defer redirectsMut.Unlock()
 	response := Response{
-		Response: httpResponse,
-		Body:     []byte(body),
-		Request:  req,
+		Response:  httpResponse,
+		Body:      []byte(body),
+		Request:   req,
+		Redirects: redirects,
 	}
 
 	return &response, nil
@@ -283,9 +391,7 @@ func SetDefaultHeader(header http.Header, key string, value string) http.Header
 func ConvertHeaderToMap(header http.Header) map[string]interface{} {
 	m := make(map[string]interface{})
 	for key, values := range header {
-		for _, value := range values {
-			m[key] = value
-		}
+		m[key] = strings.Join(values, ", ")
 	}
 	return m
 }
@@ -299,10 +405,14 @@ func ConvertMapToHeader(m map[string]interface{}) http.Header {
 	return header
 }
 
+// redirectCountKey is the context key of redirects followed before request, see Request.RedirectCount
+type redirectCountKey int
+
 // NewRedirectionHandler returns maximum allowed redirection function with provided maxRedirect
 func NewRedirectionHandler(maxRedirect int) func(req *http.Request, via []*http.Request) error {
 	return func(req *http.Request, via []*http.Request) error {
-		if len(via) >= maxRedirect {
+		redirectCount, _ := req.Context().Value(redirectCountKey(0)).(int)
+		if len(via)+redirectCount >= maxRedirect {
 			return fmt.Errorf("stopped after %d redirects", maxRedirect)
 		}
 		return nil
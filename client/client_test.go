@@ -147,6 +147,43 @@ func TestRetry(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestRedirects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/first":
+			w.Header().Set("X-Hop", "1")
+			http.Redirect(w, r, "/second", http.StatusMovedPermanently)
+		case "/second":
+			w.Header().Set("X-Hop", "2")
+			http.Redirect(w, r, "/final", http.StatusFound)
+		default:
+			fmt.Fprint(w, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	req, _ := NewRequest("GET", ts.URL+"/first", nil)
+	res, err := newClientDefault().DoRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "/final", string(res.Body))
+	if assert.Len(t, res.Redirects, 2) {
+		assert.Equal(t, ts.URL+"/first", res.Redirects[0].URL.String())
+		assert.Equal(t, http.StatusMovedPermanently, res.Redirects[0].StatusCode)
+		assert.Equal(t, "1", res.Redirects[0].Header.Get("X-Hop"))
+		assert.Equal(t, ts.URL+"/second", res.Redirects[1].URL.String())
+		assert.Equal(t, http.StatusFound, res.Redirects[1].StatusCode)
+		assert.Equal(t, "2", res.Redirects[1].Header.Get("X-Hop"))
+	}
+
+	req, _ = NewRequest("GET", ts.URL+"/first", nil)
+	req.RedirectsDisabled = true
+	res, err = newClientDefault().DoRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMovedPermanently, res.StatusCode)
+	assert.Equal(t, "/second", res.Header.Get("Location"))
+	assert.Empty(t, res.Redirects)
+}
+
 // newClientDefault creates new client with default options
 func newClientDefault() *Client {
 	return NewClient(&Options{
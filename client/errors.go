@@ -0,0 +1,153 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Cancellation reasons of built-in middlewares, see CancelledError
+var (
+	ErrDuplicateRequest = errors.New("duplicate request")
+	ErrDomainNotAllowed = errors.New("domain not allowed")
+)
+
+// HTTPStatusError is the error of responses with non-2xx status codes.
+// Response is still available, for handling errors using response body.
+type HTTPStatusError struct {
+	StatusCode int
+	Response   *Response
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.Response != nil && e.Response.Request != nil {
+		return fmt.Sprintf("http status %d: %s", e.StatusCode, e.Response.Request.URL)
+	}
+	return fmt.Sprintf("http status %d", e.StatusCode)
+}
+
+// DNSError is the error of failed host lookups
+type DNSError struct {
+	Host string
+	Err  error
+}
+
+func (e *DNSError) Error() string { return fmt.Sprintf("dns lookup %s: %v", e.Host, e.Err) }
+func (e *DNSError) Unwrap() error { return e.Err }
+
+// ConnectTimeoutError is the error of connections that couldn't be established in time
+type ConnectTimeoutError struct {
+	Err error
+}
+
+func (e *ConnectTimeoutError) Error() string { return fmt.Sprintf("connect timeout: %v", e.Err) }
+func (e *ConnectTimeoutError) Unwrap() error { return e.Err }
+
+// TLSError is the error of TLS handshakes and certificate verifications
+type TLSError struct {
+	Err error
+}
+
+func (e *TLSError) Error() string { return fmt.Sprintf("tls: %v", e.Err) }
+func (e *TLSError) Unwrap() error { return e.Err }
+
+// BodyTooLargeError is the error of response bodies exceeding max body size
+type BodyTooLargeError struct {
+	MaxBodySize int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("body exceeds max body size of %d bytes", e.MaxBodySize)
+}
+
+// CharsetError is the error of response charset detection
+type CharsetError struct {
+	ContentType string
+	Err         error
+}
+
+func (e *CharsetError) Error() string {
+	return fmt.Sprintf("charset detection error on content-type %s: %v", e.ContentType, e.Err)
+}
+func (e *CharsetError) Unwrap() error { return e.Err }
+
+// RenderError is the error of rendering requests in browser
+type RenderError struct {
+	Err error
+}
+
+func (e *RenderError) Error() string { return fmt.Sprintf("request getting rendered: %v", e.Err) }
+func (e *RenderError) Unwrap() error { return e.Err }
+
+// RobotsForbiddenError is the cancellation reason of requests forbidden by robots.txt
+type RobotsForbiddenError struct {
+	URL string
+}
+
+func (e *RobotsForbiddenError) Error() string { return "forbidden by robots.txt: " + e.URL }
+
+// CancelledError is the error of requests cancelled by middlewares.
+// Reason is the error given to Request.CancelWithReason, like ErrDuplicateRequest or *RobotsForbiddenError.
+type CancelledError struct {
+	Reason error
+}
+
+func (e *CancelledError) Error() string {
+	if e.Reason == nil {
+		return "request cancelled"
+	}
+	return fmt.Sprintf("request cancelled: %v", e.Reason)
+}
+func (e *CancelledError) Unwrap() error { return e.Reason }
+
+// RetriesExhaustedError is the error of requests that failed after all retries
+type RetriesExhaustedError struct {
+	Retries int
+	Err     error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("failed after %d retries: %v", e.Retries, e.Err)
+}
+func (e *RetriesExhaustedError) Unwrap() error { return e.Err }
+
+// classifyError wraps network errors of requests with typed errors if possible
+func classifyError(err error) error {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return &DNSError{Host: dnsErr.Name, Err: err}
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout() {
+		return &ConnectTimeoutError{Err: err}
+	}
+
+	if isTLSError(err) {
+		return &TLSError{Err: err}
+	}
+
+	return err
+}
+
+// isTLSError reports whether err is a TLS handshake or certificate verification error.
+// Only typed errors are checked. Alerts of peers are returned by crypto/tls as *net.OpError with "remote error" op.
+func isTLSError(err error) bool {
+	var recordHeaderErr tls.RecordHeaderError
+	var recordHeaderErrPtr *tls.RecordHeaderError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateInvalidErr x509.CertificateInvalidError
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &recordHeaderErr), errors.As(err, &recordHeaderErrPtr),
+		errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &certificateInvalidErr), isCertificateVerificationError(err), isAlertError(err):
+		return true
+	case errors.As(err, &opErr):
+		return opErr.Op == "remote error"
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+//go:build !go1.20
+// +build !go1.20
+
+package client
+
+// isCertificateVerificationError reports whether err is a certificate verification error of TLS handshakes.
+// tls.CertificateVerificationError is only available since Go 1.20.
+func isCertificateVerificationError(err error) bool {
+	return false
+}
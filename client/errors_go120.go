@@ -0,0 +1,15 @@
+//go:build go1.20
+// +build go1.20
+
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+)
+
+// isCertificateVerificationError reports whether err is a certificate verification error of TLS handshakes
+func isCertificateVerificationError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	return errors.As(err, &verificationErr)
+}
@@ -0,0 +1,15 @@
+//go:build go1.21
+// +build go1.21
+
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+)
+
+// isAlertError reports whether err is a TLS alert
+func isAlertError(err error) bool {
+	var alertErr tls.AlertError
+	return errors.As(err, &alertErr)
+}
@@ -0,0 +1,19 @@
+//go:build go1.21
+// +build go1.21
+
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyAlertError(t *testing.T) {
+	var tlsErr *TLSError
+	err := classifyError(fmt.Errorf("handshake: %w", tls.AlertError(40)))
+	assert.True(t, errors.As(err, &tlsErr))
+}
@@ -0,0 +1,10 @@
+//go:build !go1.21
+// +build !go1.21
+
+package client
+
+// isAlertError reports whether err is a TLS alert.
+// tls.AlertError is only available since Go 1.21.
+func isAlertError(err error) bool {
+	return false
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	var dnsErr *DNSError
+	err := classifyError(&net.OpError{Op: "dial", Err: &net.DNSError{Name: "example.invalid", Err: "no such host"}})
+	assert.True(t, errors.As(err, &dnsErr))
+	assert.Equal(t, "example.invalid", dnsErr.Host)
+
+	var timeoutErr *ConnectTimeoutError
+	err = classifyError(&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded})
+	assert.True(t, errors.As(err, &timeoutErr))
+	err = classifyError(&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded})
+	assert.False(t, errors.As(err, &timeoutErr))
+
+	var tlsErr *TLSError
+	err = classifyError(fmt.Errorf("get: %w", x509.HostnameError{Certificate: &x509.Certificate{}, Host: "example.com"}))
+	assert.True(t, errors.As(err, &tlsErr))
+	err = classifyError(fmt.Errorf("get: %w", &tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}))
+	assert.True(t, errors.As(err, &tlsErr))
+
+	err = classifyError(fmt.Errorf("get: %w", &net.OpError{Op: "remote error", Err: errors.New("tls: handshake failure")}))
+	assert.True(t, errors.As(err, &tlsErr))
+
+	// Messages aren't matched
+	plainErr := errors.New("plain")
+	assert.Equal(t, plainErr, classifyError(plainErr))
+	messageErr := errors.New("tls: unknown")
+	assert.Equal(t, messageErr, classifyError(messageErr))
+}
+
+func TestTLSError(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	req, _ := NewRequest("GET", ts.URL, nil)
+	_, err := NewClient(&Options{MaxBodySize: DefaultMaxBody, RetryTimes: -1}).DoRequest(req)
+	var tlsErr *TLSError
+	assert.True(t, errors.As(err, &tlsErr))
+}
+
+func TestRetriesExhaustedError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer ts.Close()
+
+	req, _ := NewRequest("GET", ts.URL, nil)
+	_, err := newClientDefault().DoRequest(req)
+	var retriesErr *RetriesExhaustedError
+	if assert.True(t, errors.As(err, &retriesErr)) {
+		assert.Equal(t, DefaultRetryTimes, retriesErr.Retries)
+	}
+}
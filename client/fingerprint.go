@@ -0,0 +1,164 @@
+package client
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RequestFingerprinter returns unique identifiers of requests.
+// Requests with the same fingerprint are considered the same, for duplicate filtering and caching.
+type RequestFingerprinter interface {
+	Fingerprint(req *http.Request) (string, error)
+}
+
+// Canonicalization is the set of rules to canonicalize URLs,
+// so that URLs pointing to the same resource are considered the same.
+type Canonicalization struct {
+	// Sort query parameters by key and value
+	SortQuery bool
+
+	// Strip fragments, as they're not sent to servers
+	StripFragment bool
+
+	// Lowercase host and remove default ports
+	LowercaseHost bool
+
+	// Query parameters to strip, like tracking parameters.
+	// Parameters ending with * are prefixes, like "utm_*"
+	StripParams []string
+}
+
+// DefaultCanonicalization is the default URL canonicalization rules
+var DefaultCanonicalization = Canonicalization{
+	SortQuery:     true,
+	StripFragment: true,
+	LowercaseHost: true,
+	StripParams:   []string{"utm_*", "gclid", "fbclid"},
+}
+
+// CanonicalURL returns canonical form of u, according to rules in c
+func (c *Canonicalization) CanonicalURL(u *url.URL) string {
+	canonical := *u
+
+	if c.LowercaseHost {
+		canonical.Host = strings.ToLower(canonical.Host)
+		if (canonical.Scheme == "http" && strings.HasSuffix(canonical.Host, ":80")) ||
+			(canonical.Scheme == "https" && strings.HasSuffix(canonical.Host, ":443")) {
+			canonical.Host = canonical.Host[:strings.LastIndex(canonical.Host, ":")]
+		}
+	}
+
+	if c.StripFragment {
+		canonical.Fragment = ""
+		canonical.RawFragment = ""
+	}
+
+	if c.SortQuery || len(c.StripParams) != 0 {
+		var params []string
+		for _, param := range strings.Split(canonical.RawQuery, "&") {
+			if param == "" || c.stripParam(param) {
+				continue
+			}
+			params = append(params, param)
+		}
+		if c.SortQuery {
+			sort.Strings(params)
+		}
+		canonical.RawQuery = strings.Join(params, "&")
+		canonical.ForceQuery = false
+	}
+
+	return canonical.String()
+}
+
+func (c *Canonicalization) stripParam(param string) bool {
+	key := param
+	if i := strings.IndexByte(param, '='); i != -1 {
+		key = param[:i]
+	}
+	if unescaped, err := url.QueryUnescape(key); err == nil {
+		key = unescaped
+	}
+	for _, strip := range c.StripParams {
+		if strings.HasSuffix(strip, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(strip, "*")) {
+				return true
+			}
+		} else if key == strip {
+			return true
+		}
+	}
+	return false
+}
+
+// Fingerprinter is the default RequestFingerprinter.
+// Fingerprint is the hash of method, canonical URL, selected headers and body.
+type Fingerprinter struct {
+	// Canonicalization rules applied to URLs
+	Canonicalization Canonicalization
+
+	// Headers to include in fingerprint, like Accept-Language or Authorization
+	Headers []string
+}
+
+// DefaultFingerprinter is used for duplicate filtering if no fingerprinter is set.
+// Cache keys are URLs unless a fingerprinter is set.
+var DefaultFingerprinter RequestFingerprinter = NewFingerprinter()
+
+// ErrBodyNotReplayable is returned when request body can't be read without consuming it
+var ErrBodyNotReplayable = errors.New("request body can't be read without consuming it, create requests with NewRequest")
+
+// NewFingerprinter creates a new Fingerprinter with DefaultCanonicalization
+func NewFingerprinter() *Fingerprinter {
+	return &Fingerprinter{Canonicalization: DefaultCanonicalization}
+}
+
+// Fingerprint returns the hex encoded SHA1 hash of request.
+// Request body is read using GetBody, so request isn't changed. Requests created with NewRequest always have it.
+func (f *Fingerprinter) Fingerprint(req *http.Request) (string, error) {
+	hash := sha1.New()
+	io.WriteString(hash, req.Method)
+	io.WriteString(hash, "\n")
+	io.WriteString(hash, f.Canonicalization.CanonicalURL(req.URL))
+	io.WriteString(hash, "\n")
+
+	headers := make([]string, len(f.Headers))
+	for i, header := range f.Headers {
+		headers[i] = http.CanonicalHeaderKey(header)
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		io.WriteString(hash, header+":"+strings.Join(req.Header.Values(header), ",")+"\n")
+	}
+
+	body, err := requestBody(req)
+	if err != nil {
+		return "", err
+	}
+	if body != nil {
+		_, err = io.Copy(hash, body)
+		body.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// requestBody returns a copy of request body without consuming it
+func requestBody(req *http.Request) (io.ReadCloser, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, ErrBodyNotReplayable
+	}
+	return req.GetBody()
+}
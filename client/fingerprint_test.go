@@ -0,0 +1,81 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalization_CanonicalURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		canonical string
+	}{
+		{"https://example.com/path?b=2&a=1", "https://example.com/path?a=1&b=2"},
+		{"https://Example.COM:443/Path", "https://example.com/Path"},
+		{"http://example.com:8080/", "http://example.com:8080/"},
+		{"https://example.com/?a=1#section", "https://example.com/?a=1"},
+		{"https://example.com/?utm_source=x&id=5&utm_medium=y&gclid=z", "https://example.com/?id=5"},
+		{"https://example.com/?utm_source=x", "https://example.com/"},
+		{"https://example.com/?q=a%20b&a=1", "https://example.com/?a=1&q=a%20b"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.canonical, DefaultCanonicalization.CanonicalURL(u), tt.url)
+	}
+
+	u, _ := url.Parse("https://Example.com/?b=1&a=2#top")
+	noRules := Canonicalization{}
+	assert.Equal(t, "https://Example.com/?b=1&a=2#top", noRules.CanonicalURL(u))
+}
+
+func TestFingerprinter_Fingerprint(t *testing.T) {
+	fingerprinter := NewFingerprinter()
+	fingerprint := func(method, rawURL, body string, header ...string) string {
+		req, err := NewRequest(method, rawURL, strings.NewReader(body))
+		assert.NoError(t, err)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		fp, err := fingerprinter.Fingerprint(req.Request)
+		assert.NoError(t, err)
+		return fp
+	}
+
+	assert.Equal(t, fingerprint("GET", "https://example.com/?a=1&b=2", ""), fingerprint("GET", "https://example.com/?b=2&a=1#x", ""))
+	assert.NotEqual(t, fingerprint("GET", "https://example.com/", ""), fingerprint("HEAD", "https://example.com/", ""))
+	assert.Equal(t, fingerprint("POST", "https://example.com/", "a=1"), fingerprint("POST", "https://example.com/", "a=1"))
+	assert.NotEqual(t, fingerprint("POST", "https://example.com/", "a=1"), fingerprint("POST", "https://example.com/", "a=2"))
+
+	// Headers are ignored unless selected
+	assert.Equal(t, fingerprint("GET", "https://example.com/", "", "Accept-Language", "en"), fingerprint("GET", "https://example.com/", "", "Accept-Language", "tr"))
+	fingerprinter.Headers = []string{"accept-language"}
+	assert.NotEqual(t, fingerprint("GET", "https://example.com/", "", "Accept-Language", "en"), fingerprint("GET", "https://example.com/", "", "Accept-Language", "tr"))
+}
+
+func TestFingerprinter_FingerprintKeepsBody(t *testing.T) {
+	req, err := NewRequest("POST", "https://example.com/", ioutil.NopCloser(strings.NewReader("data")))
+	assert.NoError(t, err)
+	_, err = NewFingerprinter().Fingerprint(req.Request)
+	assert.NoError(t, err)
+
+	body, err := ioutil.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(body))
+}
+
+func TestFingerprinter_FingerprintDoesntConsumeBody(t *testing.T) {
+	req, err := http.NewRequest("POST", "https://example.com/", ioutil.NopCloser(strings.NewReader("data")))
+	assert.NoError(t, err)
+	_, err = NewFingerprinter().Fingerprint(req)
+	assert.ErrorIs(t, err, ErrBodyNotReplayable)
+
+	body, err := ioutil.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(body))
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"io"
+)
+
+// MaxBodyAction is the action taken when response body exceeds max body size
+type MaxBodyAction int
+
+const (
+	// MaxBodyTruncate truncates body to max body size and sets Response.Truncated
+	MaxBodyTruncate MaxBodyAction = iota
+
+	// MaxBodyWarn is MaxBodyTruncate, also logging a warning
+	MaxBodyWarn
+
+	// MaxBodyAbort fails request with *BodyTooLargeError.
+	// Responses with larger Content-Length are rejected without reading body.
+	MaxBodyAbort
+)
+
+// maxBodyReader reads up to n bytes like io.LimitReader, and records if there's more
+type maxBodyReader struct {
+	r        io.Reader
+	n        int64
+	abort    bool
+	max      int64
+	exceeded bool
+}
+
+func (l *maxBodyReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		var b [1]byte
+		if n, _ := io.ReadFull(l.r, b[:]); n != 0 {
+			l.exceeded = true
+			if l.abort {
+				return 0, &BodyTooLargeError{MaxBodySize: l.max}
+			}
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
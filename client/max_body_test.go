@@ -0,0 +1,53 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxBody(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if r.URL.Path == "/chunked" {
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	newClient := func(maxBodySize int64, action MaxBodyAction) *Client {
+		return NewClient(&Options{MaxBodySize: maxBodySize, MaxBodyAction: action, RetryTimes: DefaultRetryTimes})
+	}
+
+	for _, path := range []string{"/", "/chunked"} {
+		req, _ := NewRequest("GET", ts.URL+path, nil)
+		res, err := newClient(10, MaxBodyTruncate).DoRequest(req)
+		assert.NoError(t, err)
+		assert.Equal(t, body[:10], string(res.Body))
+		assert.True(t, res.Truncated)
+
+		req, _ = NewRequest("GET", ts.URL+path, nil)
+		res, err = newClient(100, MaxBodyAbort).DoRequest(req)
+		assert.NoError(t, err)
+		assert.Equal(t, body, string(res.Body))
+		assert.False(t, res.Truncated)
+
+		req, _ = NewRequest("GET", ts.URL+path, nil)
+		_, err = newClient(10, MaxBodyAbort).DoRequest(req)
+		var bodyTooLargeErr *BodyTooLargeError
+		assert.True(t, errors.As(err, &bodyTooLargeErr), path)
+
+		// Request overrides client max body size
+		req, _ = NewRequest("GET", ts.URL+path, nil)
+		req.MaxBodySize = 1000
+		res, err = newClient(10, MaxBodyAbort).DoRequest(req)
+		assert.NoError(t, err)
+		assert.Equal(t, body, string(res.Body))
+	}
+}
@@ -0,0 +1,459 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/geziyor/geziyor/internal"
+	"github.com/geziyor/geziyor/metrics"
+)
+
+var (
+	// ErrNoProxyAvailable is the error type for proxy pools that all proxies are benched
+	ErrNoProxyAvailable = errors.New("no proxy available")
+)
+
+// Default values for proxy pool
+const (
+	DefaultProxyMaxFailures      = 3
+	DefaultProxyBenchDuration    = time.Minute
+	DefaultProxyBanBenchDuration = 10 * time.Minute
+)
+
+var (
+	DefaultProxyBanHTTPCodes = []int{403, 429}
+	DefaultProxyBanHeaders   = http.Header{
+		"Cf-Mitigated": {"challenge"},
+	}
+	// DefaultProxyBanPatterns match titles of full page challenges and block pages, not captcha widgets in pages
+	DefaultProxyBanPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)<title>\s*(Just a moment\.\.\.|Attention Required! \| Cloudflare)\s*</title>`),
+		regexp.MustCompile(`(?i)<title>\s*Access Denied\s*</title>`),
+		regexp.MustCompile(`(?i)<title>\s*(Access to this page has been denied|Pardon Our Interruption)\.?\s*</title>`),
+	}
+)
+
+// ProxySelection is the strategy used to select the next proxy from the pool
+type ProxySelection int
+
+const (
+	// RoundRobinSelection rotates proxies on every request.
+	RoundRobinSelection ProxySelection = iota
+
+	// WeightedSelection selects proxies randomly, proportional to their Weight.
+	WeightedSelection
+)
+
+type proxyTraceKey int
+
+// proxyTrace is used to learn which proxy is selected for a request, and whether a connection is established
+type proxyTrace struct {
+	proxy     *Proxy
+	connected int32 // set when a connection to proxy is dialed
+	gotConn   int32 // set when a connection to target is ready
+}
+
+// Proxy is a proxy in ProxyPool, tracking its own health.
+type Proxy struct {
+	URL *url.URL
+
+	// Weight is used by WeightedSelection. Default: 1
+	Weight int
+
+	mut                 sync.Mutex
+	successes           int64
+	failures            int64
+	bans                int64
+	consecutiveFailures int
+	totalLatency        time.Duration
+	benchedUntil        time.Time
+}
+
+// ProxyStats is a snapshot of a proxy's health
+type ProxyStats struct {
+	URL          string
+	Successes    int64
+	Failures     int64
+	Bans         int64
+	AvgLatency   time.Duration
+	BenchedUntil time.Time
+}
+
+// ProxyPool is a proxy manager that tracks health of its proxies.
+// Proxies are benched temporarily after consecutive connection errors or ban signals.
+type ProxyPool struct {
+	// Selection strategy. Default: RoundRobinSelection
+	Selection ProxySelection
+
+	// If true, the same proxy is used for every request to a domain, as long as it's not benched.
+	StickyPerDomain bool
+
+	// Number of consecutive connection errors to bench proxy. Default: 3
+	MaxFailures int
+
+	// Bench duration after connection errors. Default: 1 minute
+	BenchDuration time.Duration
+
+	// Bench duration after ban signals. Default: 10 minutes
+	BanBenchDuration time.Duration
+
+	// HTTP response codes that are considered as ban signal.
+	// Default: []int{403, 429}
+	BanHTTPCodes []int
+
+	// Response headers that are considered as ban signal, like challenge headers. A header is matched
+	// if any of its values equals to one of the values here, case-insensitively.
+	// Default: DefaultProxyBanHeaders
+	BanHeaders http.Header
+
+	// Response body patterns that are considered as ban signal, like captcha pages.
+	// They're checked on every response, so they shouldn't match pages embedding captcha widgets, like login forms.
+	// Set an empty slice to disable. Default: DefaultProxyBanPatterns
+	BanPatterns []*regexp.Regexp
+
+	// Metrics to export per-proxy stats. Set by Geziyor if empty.
+	Metrics *metrics.Metrics
+
+	proxies []*Proxy
+	index   uint32
+	sticky  sync.Map
+}
+
+// NewProxyPool creates a new proxy pool with provided proxy URLs.
+// The proxy type is determined by the URL scheme. "http", "https"
+// and "socks5" are supported. If the scheme is empty,
+// "http" is assumed.
+func NewProxyPool(proxyURLs ...string) (*ProxyPool, error) {
+	pool := &ProxyPool{}
+	for _, proxyURL := range proxyURLs {
+		if err := pool.Add(proxyURL, 1); err != nil {
+			return nil, err
+		}
+	}
+	return pool, nil
+}
+
+// NewProxyPoolFromFile creates a new proxy pool with proxies in file.
+// Each line contains a proxy URL and an optional weight, separated by whitespace.
+// Empty lines and lines starting with # are ignored.
+func NewProxyPoolFromFile(fileName string) (*ProxyPool, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("proxy file open: %w", err)
+	}
+	defer file.Close()
+
+	pool := &ProxyPool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		weight := 1
+		if len(fields) > 1 {
+			weight, err = strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("proxy weight parse: %w", err)
+			}
+		}
+		if err := pool.Add(fields[0], weight); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("proxy file read: %w", err)
+	}
+	return pool, nil
+}
+
+// Add adds a new proxy to pool. Should be called before scraping started.
+func (p *ProxyPool) Add(proxyURL string, weight int) error {
+	parsedURL, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("proxy url parse: %w", err)
+	}
+	if weight < 1 {
+		weight = 1
+	}
+	p.proxies = append(p.proxies, &Proxy{URL: parsedURL, Weight: weight})
+	return nil
+}
+
+// Stats returns health snapshots of all proxies in pool
+func (p *ProxyPool) Stats() []ProxyStats {
+	stats := make([]ProxyStats, 0, len(p.proxies))
+	for _, proxy := range p.proxies {
+		proxy.mut.Lock()
+		stat := ProxyStats{
+			URL:          proxy.URL.String(),
+			Successes:    proxy.successes,
+			Failures:     proxy.failures,
+			Bans:         proxy.bans,
+			BenchedUntil: proxy.benchedUntil,
+		}
+		if proxy.successes != 0 {
+			stat.AvgLatency = proxy.totalLatency / time.Duration(proxy.successes)
+		}
+		proxy.mut.Unlock()
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// GetProxy selects a proxy that is not benched. Can be used as ProxyFunc.
+func (p *ProxyPool) GetProxy(pr *http.Request) (*url.URL, error) {
+	proxy := p.selectProxy(pr.URL.Hostname())
+	if proxy == nil {
+		return nil, ErrNoProxyAvailable
+	}
+
+	if trace, ok := pr.Context().Value(proxyTraceKey(0)).(*proxyTrace); ok {
+		trace.proxy = proxy
+	}
+
+	// Set proxy url to context
+	ctx := context.WithValue(pr.Context(), ProxyURLKey(0), proxy.URL.String())
+	*pr = *pr.WithContext(ctx)
+	return proxy.URL, nil
+}
+
+func (p *ProxyPool) selectProxy(host string) *Proxy {
+	if p.StickyPerDomain {
+		if proxy, ok := p.sticky.Load(host); ok && !proxy.(*Proxy).benched() {
+			return proxy.(*Proxy)
+		}
+	}
+
+	var available []*Proxy
+	for _, proxy := range p.proxies {
+		if !proxy.benched() {
+			available = append(available, proxy)
+		}
+	}
+	if len(available) == 0 {
+		return nil
+	}
+
+	var proxy *Proxy
+	switch p.Selection {
+	case WeightedSelection:
+		total := 0
+		for _, candidate := range available {
+			total += candidate.Weight
+		}
+		n := rand.Intn(total)
+		for _, candidate := range available {
+			if n < candidate.Weight {
+				proxy = candidate
+				break
+			}
+			n -= candidate.Weight
+		}
+	default:
+		index := atomic.AddUint32(&p.index, 1) - 1
+		proxy = available[index%uint32(len(available))]
+	}
+
+	if p.StickyPerDomain {
+		p.sticky.Store(host, proxy)
+	}
+	return proxy
+}
+
+// withTrace returns a copy of request whose selected proxy will be recorded to returned trace
+func (p *ProxyPool) withTrace(req *http.Request) (*http.Request, *proxyTrace) {
+	trace := &proxyTrace{}
+	ctx := context.WithValue(req.Context(), proxyTraceKey(0), trace)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				atomic.StoreInt32(&trace.connected, 1)
+			}
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			atomic.StoreInt32(&trace.gotConn, 1)
+		},
+	})
+	return req.WithContext(ctx), trace
+}
+
+// socksTargetErrors are SOCKS replies about target, rather than the proxy
+var socksTargetErrors = []string{"host unreachable", "network unreachable", "connection refused", "TTL expired"}
+
+// connectTargetErrors are CONNECT responses about target, rather than the proxy
+var connectTargetErrors = []string{"Bad Gateway", "Gateway Timeout"}
+
+// isProxyError reports whether err is an error of connecting to the proxy or of handshaking with it,
+// so that errors of target sites, like DNS and TLS errors, and cancellations don't bench healthy proxies.
+func isProxyError(trace *proxyTrace, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// Dialing proxy or TLS handshake with proxy
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		if opErr.Op == "proxyconnect" {
+			return true
+		}
+		if strings.HasPrefix(opErr.Op, "socks") {
+			for _, targetErr := range socksTargetErrors {
+				if strings.Contains(opErr.Err.Error(), targetErr) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	// CONNECT refused by proxy: connected to proxy, but not to target
+	if atomic.LoadInt32(&trace.connected) == 1 && atomic.LoadInt32(&trace.gotConn) == 0 {
+		var netErr net.Error
+		if isTLSError(err) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return false
+		}
+		for _, targetErr := range connectTargetErrors {
+			if err.Error() == targetErr || strings.HasSuffix(err.Error(), ": "+targetErr) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// observe updates health of the proxy used for request, using response status, headers, body and error
+func (p *ProxyPool) observe(trace *proxyTrace, statusCode int, header http.Header, body []byte, err error, latency time.Duration) {
+	proxy := trace.proxy
+	if proxy == nil {
+		return
+	}
+	proxyLabel := proxy.URL.Redacted()
+
+	if err != nil {
+		// Errors of target sites and cancellations don't tell about proxy health
+		if !isProxyError(trace, err) {
+			return
+		}
+		p.Metrics.ProxyRequestCounter.With("proxy", proxyLabel, "result", "failure").Add(1)
+		if proxy.fail(internal.DefaultInt(p.MaxFailures, DefaultProxyMaxFailures), p.benchDuration()) {
+			p.Metrics.ProxyBenchedCounter.With("proxy", proxyLabel, "reason", "failure").Add(1)
+			internal.Logger.Printf("Proxy benched after connection errors: %s\n", proxyLabel)
+		}
+		return
+	}
+
+	if p.isBanned(statusCode, header, body) {
+		p.Metrics.ProxyRequestCounter.With("proxy", proxyLabel, "result", "banned").Add(1)
+		p.Metrics.ProxyBenchedCounter.With("proxy", proxyLabel, "reason", "banned").Add(1)
+		proxy.ban(p.banBenchDuration())
+		internal.Logger.Printf("Proxy benched after ban signal (%d): %s\n", statusCode, proxyLabel)
+		return
+	}
+
+	p.Metrics.ProxyRequestCounter.With("proxy", proxyLabel, "result", "success").Add(1)
+	p.Metrics.ProxyLatencyHistogram.With("proxy", proxyLabel).Observe(latency.Seconds())
+	proxy.succeed(latency)
+}
+
+func (p *ProxyPool) isBanned(statusCode int, header http.Header, body []byte) bool {
+	banHTTPCodes := p.BanHTTPCodes
+	if len(banHTTPCodes) == 0 {
+		banHTTPCodes = DefaultProxyBanHTTPCodes
+	}
+	if internal.ContainsInt(banHTTPCodes, statusCode) {
+		return true
+	}
+
+	banHeaders := p.BanHeaders
+	if banHeaders == nil {
+		banHeaders = DefaultProxyBanHeaders
+	}
+	for name, banValues := range banHeaders {
+		for _, value := range header.Values(name) {
+			for _, banValue := range banValues {
+				if strings.EqualFold(strings.TrimSpace(value), banValue) {
+					return true
+				}
+			}
+		}
+	}
+
+	banPatterns := p.BanPatterns
+	if banPatterns == nil {
+		banPatterns = DefaultProxyBanPatterns
+	}
+	for _, pattern := range banPatterns {
+		if pattern.Match(body) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ProxyPool) benchDuration() time.Duration {
+	if p.BenchDuration == 0 {
+		return DefaultProxyBenchDuration
+	}
+	return p.BenchDuration
+}
+
+func (p *ProxyPool) banBenchDuration() time.Duration {
+	if p.BanBenchDuration == 0 {
+		return DefaultProxyBanBenchDuration
+	}
+	return p.BanBenchDuration
+}
+
+func (p *Proxy) benched() bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	return time.Now().Before(p.benchedUntil)
+}
+
+func (p *Proxy) succeed(latency time.Duration) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.successes++
+	p.consecutiveFailures = 0
+	p.totalLatency += latency
+}
+
+// fail records a connection error and benches proxy if maxFailures reached.
+// Returns true if proxy is benched.
+func (p *Proxy) fail(maxFailures int, benchDuration time.Duration) bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.failures++
+	p.consecutiveFailures++
+	if p.consecutiveFailures >= maxFailures {
+		p.consecutiveFailures = 0
+		p.benchedUntil = time.Now().Add(benchDuration)
+		return true
+	}
+	return false
+}
+
+func (p *Proxy) ban(benchDuration time.Duration) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.bans++
+	p.consecutiveFailures = 0
+	p.benchedUntil = time.Now().Add(benchDuration)
+}
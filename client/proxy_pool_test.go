@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyPoolBansProxy(t *testing.T) {
+	banned := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer banned.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "healthy")
+	}))
+	defer healthy.Close()
+
+	pool, err := NewProxyPool(banned.URL, healthy.URL)
+	assert.NoError(t, err)
+	c := NewClient(&Options{MaxBodySize: DefaultMaxBody, RetryTimes: -1, ProxyPool: pool})
+
+	for i := 0; i < 4; i++ {
+		req, _ := NewRequest("GET", "http://example.com", nil)
+		_, err := c.DoRequest(req)
+		assert.NoError(t, err)
+	}
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(1), stats[0].Bans)
+	assert.False(t, stats[0].BenchedUntil.IsZero())
+	assert.Equal(t, int64(3), stats[1].Successes)
+}
+
+func TestProxyPoolBanSignals(t *testing.T) {
+	pool, err := NewProxyPool("http://proxy1")
+	assert.NoError(t, err)
+
+	challenge := http.Header{"Cf-Mitigated": {"Challenge"}}
+	assert.True(t, pool.isBanned(http.StatusServiceUnavailable, challenge, nil))
+	assert.True(t, pool.isBanned(http.StatusForbidden, nil, nil))
+
+	// Pages embedding captcha widgets, like login forms, aren't ban signals by default
+	captchaForm := []byte(`<form><div class="g-recaptcha" data-sitekey="key"></div></form>`)
+	assert.False(t, pool.isBanned(http.StatusOK, http.Header{}, captchaForm))
+
+	// Full page challenges are
+	challengePage := []byte(`<html><head><title>Just a moment...</title></head></html>`)
+	assert.True(t, pool.isBanned(http.StatusOK, http.Header{}, challengePage))
+
+	pool.BanPatterns = []*regexp.Regexp{}
+	assert.False(t, pool.isBanned(http.StatusOK, http.Header{}, challengePage))
+}
+
+func TestProxyPoolErrorClassification(t *testing.T) {
+	trace := &proxyTrace{}
+	assert.True(t, isProxyError(trace, &net.OpError{Op: "proxyconnect", Net: "tcp", Err: errors.New("connection refused")}))
+	assert.True(t, isProxyError(trace, &net.OpError{Op: "socks connect", Net: "tcp", Err: errors.New("unknown error general SOCKS server failure")}))
+	assert.False(t, isProxyError(trace, &net.OpError{Op: "socks connect", Net: "tcp", Err: errors.New("unknown error host unreachable")}))
+	assert.False(t, isProxyError(trace, &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}))
+	assert.False(t, isProxyError(trace, context.Canceled))
+	assert.False(t, isProxyError(trace, fmt.Errorf("request: %w", context.DeadlineExceeded)))
+
+	// CONNECT refused by proxy, or target errors reported by it
+	trace = &proxyTrace{connected: 1}
+	assert.True(t, isProxyError(trace, errors.New("Proxy Authentication Required")))
+	assert.False(t, isProxyError(trace, errors.New("Bad Gateway")))
+	assert.False(t, isProxyError(trace, x509.UnknownAuthorityError{}))
+
+	// Errors after connecting to target aren't about proxy
+	trace = &proxyTrace{connected: 1, gotConn: 1}
+	assert.False(t, isProxyError(trace, errors.New("unexpected EOF")))
+}
+
+func TestProxyPoolBenchesFailingProxy(t *testing.T) {
+	pool, err := NewProxyPool("http://127.0.0.1:1")
+	assert.NoError(t, err)
+	pool.MaxFailures = 2
+	c := NewClient(&Options{MaxBodySize: DefaultMaxBody, RetryTimes: 1, ProxyPool: pool})
+
+	req, _ := NewRequest("GET", "http://example.com", nil)
+	_, err = c.DoRequest(req)
+	assert.Error(t, err)
+	assert.Equal(t, int64(2), pool.Stats()[0].Failures)
+
+	req, _ = NewRequest("GET", "http://example.com", nil)
+	_, err = c.DoRequest(req)
+	assert.ErrorIs(t, err, ErrNoProxyAvailable)
+}
+
+func TestProxyPoolStickyPerDomain(t *testing.T) {
+	pool, err := NewProxyPool("http://proxy1", "http://proxy2", "http://proxy3")
+	assert.NoError(t, err)
+	pool.StickyPerDomain = true
+
+	req, _ := http.NewRequest("GET", "http://example.com/a", nil)
+	first, err := pool.GetProxy(req)
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequest("GET", "http://example.com/b", nil)
+		u, err := pool.GetProxy(req)
+		assert.NoError(t, err)
+		assert.Equal(t, first, u)
+	}
+
+	req, _ = http.NewRequest("GET", "http://other.com", nil)
+	u, _ := pool.GetProxy(req)
+	assert.NotEqual(t, first, u)
+}
+
+func TestNewProxyPoolFromFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "proxies")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	fileName := filepath.Join(tempDir, "proxies.txt")
+	err = ioutil.WriteFile(fileName, []byte("# proxies\nhttp://proxy1:8080 5\n\nsocks5://proxy2:1080\n"), 0666)
+	assert.NoError(t, err)
+
+	pool, err := NewProxyPoolFromFile(fileName)
+	assert.NoError(t, err)
+	assert.Len(t, pool.proxies, 2)
+	assert.Equal(t, 5, pool.proxies[0].Weight)
+	assert.Equal(t, "socks5", pool.proxies[1].URL.Scheme)
+}
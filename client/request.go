@@ -1,9 +1,12 @@
 package client
 
 import (
+	"bytes"
 	"github.com/chromedp/chromedp"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 )
 
 // Request is a small wrapper around *http.Request that contains Metadata and Rendering option
@@ -25,13 +28,45 @@ type Request struct {
 	// If you're having issues with auto detection, set this.
 	Encoding string
 
+	// MaxBodySize overrides max body size of client for this request.
+	MaxBodySize int64
+
+	// SessionID selects the session whose cookie jar, proxy and headers will be used.
+	// Leave empty to use the client's defaults. See Client.AddSession
+	SessionID string
+
+	// AllowedStatusCodes are the non-2xx status codes whose responses are still passed to callback,
+	// when HTTP error filtering is enabled. See Options.HTTPErrorEnabled
+	AllowedStatusCodes []int
+
+	// If true, redirects are not followed and redirect responses are returned as they are.
+	// Only supported for non-rendered requests.
+	RedirectsDisabled bool
+
+	// RedirectCount is the number of redirects followed before this request, like meta refresh redirects.
+	// It's counted against maximum redirects.
+	RedirectCount int
+
 	// Set this true to cancel requests. Should be used on middlewares.
 	Cancelled bool
 
 	// Chrome actions to be run if the request is Rendered
 	Actions []chromedp.Action
 
+	// Referrer is the page that request is created from, like a followed link. See Response.NewRequest
+	Referrer *Referrer
+
 	retryCounter int
+	cancelReason error
+}
+
+// Referrer is the page that a request is created from
+type Referrer struct {
+	URL *url.URL
+
+	// Policy is the referrer policy of page, from its Referrer-Policy header or referrer meta tag.
+	// Empty if page has no policy.
+	Policy string
 }
 
 // Cancel request
@@ -39,12 +74,36 @@ func (r *Request) Cancel() {
 	r.Cancelled = true
 }
 
+// CancelWithReason cancels request with a reason, which is reported in *CancelledError
+func (r *Request) CancelWithReason(reason error) {
+	r.Cancelled = true
+	r.cancelReason = reason
+}
+
+// CancelReason returns the reason given to CancelWithReason
+func (r *Request) CancelReason() error {
+	return r.cancelReason
+}
+
 // NewRequest returns a new Request given a method, URL, and optional body.
+// Body is buffered if it can't be read again, so that it can be fingerprinted and retried.
 func NewRequest(method, url string, body io.Reader) (*Request, error) {
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		data, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = int64(len(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
 
 	request := Request{
 		Request: req,
@@ -2,6 +2,7 @@ package client
 
 import (
 	"github.com/PuerkitoBio/goquery"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -15,10 +16,32 @@ type Response struct {
 	// Response body
 	Body []byte
 
+	// Truncated is true if body is truncated, as it exceeds max body size
+	Truncated bool
+
 	// Goquery Document object. If response IsHTML, its non-nil.
 	HTMLDoc *goquery.Document
 
 	Request *Request
+
+	// Redirects is the redirect history of request, in order. Final response is not included.
+	Redirects []Redirect
+}
+
+// Redirect is a redirect response followed while making a request
+type Redirect struct {
+	URL        *url.URL
+	StatusCode int
+	Header     http.Header
+}
+
+// redirectHistory returns redirects followed before final response resp
+func redirectHistory(resp *http.Response) []Redirect {
+	var redirects []Redirect
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		redirects = append([]Redirect{{URL: r.Request.URL, StatusCode: r.StatusCode, Header: r.Header}}, redirects...)
+	}
+	return redirects
 }
 
 // JoinURL joins base response URL and provided relative URL.
@@ -33,6 +56,36 @@ func (r *Response) JoinURL(relativeURL string) string {
 	return joinedURL.String()
 }
 
+// NewRequest returns a new Request to URL relative to response, like a link of the page.
+// Request's Referrer is set to response, so that its Referer header can be set by Referer middleware.
+func (r *Response) NewRequest(method, relativeURL string, body io.Reader) (*Request, error) {
+	targetURL, err := r.Request.URL.Parse(relativeURL)
+	if err != nil {
+		return nil, err
+	}
+	req, err := NewRequest(method, targetURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Referrer = &Referrer{URL: r.Request.URL, Policy: r.referrerPolicy()}
+	return req, nil
+}
+
+// referrerPolicy returns the referrer policy of page. Referrer-Policy header overrides referrer meta tag.
+func (r *Response) referrerPolicy() string {
+	if r.Response != nil {
+		if policy := r.Header.Get("Referrer-Policy"); policy != "" {
+			return policy
+		}
+	}
+	if r.HTMLDoc != nil {
+		if content, exists := r.HTMLDoc.Find(`meta[name="referrer"]`).Last().Attr("content"); exists {
+			return content
+		}
+	}
+	return ""
+}
+
 // IsHTML checks if response content is HTML by looking content-type header
 func (r *Response) IsHTML() bool {
 	contentType := r.Header.Get("Content-Type")
@@ -0,0 +1,279 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/geziyor/geziyor/jar"
+)
+
+var (
+	// ErrSessionNotFound is the error type for requests with unknown session ID
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+type sessionProxyKey int
+
+// Session is an isolated identity for requests, like a logged-in user.
+// Requests with the Session's ID use its own cookie jar, proxy and headers, including rendered requests.
+// Cookies are copied to browser before rendering, and copied back to Jar after.
+type Session struct {
+	ID string
+
+	// Jar is the cookie jar of session. If nil, cookies won't send.
+	Jar http.CookieJar
+
+	// Proxy is the proxy used for every request of session. If nil, client's proxy is used.
+	// Rendered requests use it only with local Chrome, and without credentials.
+	Proxy *url.URL
+
+	// Header is set on every request of session, unless request already has it.
+	Header http.Header
+}
+
+// sessionFile is the persisted form of Session
+type sessionFile struct {
+	ID      string       `json:"id"`
+	Proxy   string       `json:"proxy,omitempty"`
+	Header  http.Header  `json:"header,omitempty"`
+	Cookies []*jar.Entry `json:"cookies,omitempty"`
+}
+
+// entriesJar is a cookie jar that can list its cookies with all attributes, like *jar.Jar
+type entriesJar interface {
+	http.CookieJar
+	Entries() []*jar.Entry
+	SetEntries(entries []*jar.Entry)
+}
+
+// NewSession creates a new Session with an empty cookie jar
+func NewSession(id string) *Session {
+	cookieJar, _ := jar.New(nil)
+	return &Session{
+		ID:     id,
+		Jar:    cookieJar,
+		Header: http.Header{},
+	}
+}
+
+// LoadSession reads a session written by Session.Save
+func LoadSession(r io.Reader) (*Session, error) {
+	var file sessionFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("session decode: %w", err)
+	}
+
+	session := NewSession(file.ID)
+	if file.Header != nil {
+		session.Header = file.Header
+	}
+	if file.Proxy != "" {
+		proxyURL, err := url.Parse(file.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("session proxy url parse: %w", err)
+		}
+		session.Proxy = proxyURL
+	}
+	session.Jar.(entriesJar).SetEntries(file.Cookies)
+	return session, nil
+}
+
+// Save writes session with its cookies, so that it can be loaded by LoadSession on next runs.
+// Cookies are saved with all of their attributes if session's Jar lists them, like *jar.Jar.
+func (s *Session) Save(w io.Writer) error {
+	file := sessionFile{
+		ID:     s.ID,
+		Header: s.Header,
+	}
+	if s.Proxy != nil {
+		file.Proxy = s.Proxy.String()
+	}
+	if cookieJar, ok := s.Jar.(entriesJar); ok {
+		file.Cookies = cookieJar.Entries()
+	} else if s.Jar != nil {
+		return fmt.Errorf("session encode: cookies of %T can't be listed", s.Jar)
+	}
+	if err := json.NewEncoder(w).Encode(file); err != nil {
+		return fmt.Errorf("session encode: %w", err)
+	}
+	return nil
+}
+
+// SetCookies handles the receipt of the cookies in a reply for the given URL
+func (s *Session) SetCookies(URL string, cookies []*http.Cookie) error {
+	if s.Jar == nil {
+		return ErrNoCookieJar
+	}
+	u, err := url.Parse(URL)
+	if err != nil {
+		return err
+	}
+	s.Jar.SetCookies(u, cookies)
+	return nil
+}
+
+// Cookies returns the cookies to send in a request for the given URL.
+func (s *Session) Cookies(URL string) []*http.Cookie {
+	if s.Jar == nil {
+		return nil
+	}
+	parsedURL, err := url.Parse(URL)
+	if err != nil {
+		return nil
+	}
+	return s.Jar.Cookies(parsedURL)
+}
+
+// AddSession adds session to client, replacing the existing one with the same ID.
+func (c *Client) AddSession(session *Session) {
+	c.sessions.Store(session.ID, session)
+}
+
+// Session returns the session with provided ID
+func (c *Client) Session(id string) (*Session, bool) {
+	session, ok := c.sessions.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return session.(*Session), true
+}
+
+// DeleteSession removes session with provided ID from client
+func (c *Client) DeleteSession(id string) {
+	c.sessions.Delete(id)
+}
+
+// sessionClient returns http.Client and http.Request to be used for request's session
+func (c *Client) sessionClient(req *Request) (*http.Client, *http.Request, error) {
+	if req.SessionID == "" {
+		return c.Client, req.Request, nil
+	}
+	session, ok := c.Session(req.SessionID)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSessionNotFound, req.SessionID)
+	}
+
+	httpClient := *c.Client
+	httpClient.Jar = session.Jar
+
+	httpRequest := req.Request
+	if session.Proxy != nil {
+		httpRequest = httpRequest.WithContext(context.WithValue(httpRequest.Context(), sessionProxyKey(0), session.Proxy))
+	}
+	return &httpClient, httpRequest, nil
+}
+
+// SetSessionHeaders sets session headers of request with all of their values, if request has a session.
+// Headers already set on request are not changed.
+func (c *Client) SetSessionHeaders(req *Request) {
+	if req.SessionID == "" {
+		return
+	}
+	if session, ok := c.Session(req.SessionID); ok {
+		for key, values := range session.Header {
+			key = http.CanonicalHeaderKey(key)
+			if len(values) != 0 && len(req.Header[key]) == 0 {
+				req.Header[key] = append([]string(nil), values...)
+			}
+		}
+	}
+}
+
+// sessionProxy returns a proxy function that prefers session proxy over the provided one
+func sessionProxy(proxyFunction func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(r *http.Request) (*url.URL, error) {
+		if proxyURL, ok := r.Context().Value(sessionProxyKey(0)).(*url.URL); ok {
+			// Set proxy url to context
+			ctx := context.WithValue(r.Context(), ProxyURLKey(0), proxyURL.String())
+			*r = *r.WithContext(ctx)
+			return proxyURL, nil
+		}
+		return proxyFunction(r)
+	}
+}
+
+// sessionBrowserActions returns actions that set session cookies to browser before rendering,
+// and actions that store browser cookies to session after rendering.
+func sessionBrowserActions(session *Session, req *Request) ([]chromedp.Action, []chromedp.Action) {
+	if session.Jar == nil {
+		return nil, nil
+	}
+
+	var params []*network.CookieParam
+	if cookieJar, ok := session.Jar.(entriesJar); ok {
+		for _, entry := range cookieJar.Entries() {
+			param := &network.CookieParam{
+				Name:     entry.Name,
+				Value:    entry.Value,
+				Path:     entry.Path,
+				Secure:   entry.Secure,
+				HTTPOnly: entry.HttpOnly,
+			}
+			// Host-only cookies are set by URL, as cookies with domain are sent to subdomains too
+			if entry.HostOnly {
+				param.URL = entry.URL().String()
+			} else {
+				param.Domain = "." + entry.Domain
+			}
+			if !entry.Expires.IsZero() {
+				expires := cdp.TimeSinceEpoch(entry.Expires)
+				param.Expires = &expires
+			}
+			params = append(params, param)
+		}
+	} else {
+		for _, cookie := range session.Jar.Cookies(req.URL) {
+			params = append(params, &network.CookieParam{Name: cookie.Name, Value: cookie.Value, URL: req.URL.String()})
+		}
+	}
+
+	var before []chromedp.Action
+	if len(params) != 0 {
+		before = append(before, network.SetCookies(params))
+	}
+	after := []chromedp.Action{
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			cookies, err := network.GetAllCookies().Do(ctx)
+			if err != nil {
+				return err
+			}
+			for _, cookie := range cookies {
+				entry := &jar.Entry{
+					Name:     cookie.Name,
+					Value:    cookie.Value,
+					Domain:   strings.TrimPrefix(cookie.Domain, "."),
+					Path:     cookie.Path,
+					Secure:   cookie.Secure,
+					HttpOnly: cookie.HTTPOnly,
+					HostOnly: !strings.HasPrefix(cookie.Domain, "."),
+				}
+				if !cookie.Session {
+					entry.Expires = time.Unix(int64(cookie.Expires), 0)
+				}
+				session.Jar.SetCookies(entry.URL(), []*http.Cookie{entry.Cookie()})
+			}
+			return nil
+		}),
+	}
+	return before, after
+}
+
+// sessionAllocatorOptions returns allocator options of local Chrome that use session proxy.
+// Proxy credentials are not supported by Chrome.
+func sessionAllocatorOptions(session *Session, options []chromedp.ExecAllocatorOption) []chromedp.ExecAllocatorOption {
+	if session.Proxy == nil {
+		return options
+	}
+	proxyURL := &url.URL{Scheme: session.Proxy.Scheme, Host: session.Proxy.Host}
+	return append(append([]chromedp.ExecAllocatorOption(nil), options...), chromedp.ProxyServer(proxyURL.String()))
+}
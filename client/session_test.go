@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/geziyor/geziyor/jar"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionIsolation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "user", Value: r.Header.Get("X-User")})
+			return
+		}
+		if cookie, err := r.Cookie("user"); err == nil {
+			w.Write([]byte(cookie.Value))
+		}
+	}))
+	defer ts.Close()
+
+	c := newClientDefault()
+	for _, id := range []string{"alice", "bob"} {
+		session := NewSession(id)
+		session.Header.Set("X-User", id)
+		c.AddSession(session)
+
+		req, _ := NewRequest("GET", ts.URL+"/login", nil)
+		req.SessionID = id
+		c.SetSessionHeaders(req)
+		_, err := c.DoRequest(req)
+		assert.NoError(t, err)
+	}
+
+	for _, id := range []string{"alice", "bob"} {
+		req, _ := NewRequest("GET", ts.URL, nil)
+		req.SessionID = id
+		res, err := c.DoRequest(req)
+		assert.NoError(t, err)
+		assert.Equal(t, id, string(res.Body))
+	}
+
+	c.DeleteSession("bob")
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req.SessionID = "bob"
+	_, err := c.DoRequest(req)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// Unknown sessions aren't retried
+	var retriesErr *RetriesExhaustedError
+	assert.False(t, errors.As(err, &retriesErr))
+	assert.Equal(t, 0, req.retryCounter)
+}
+
+func TestSessionSaveLoad(t *testing.T) {
+	session := NewSession("alice")
+	session.Header.Set("X-User", "alice")
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	err := session.SetCookies("https://www.example.com/account", []*http.Cookie{
+		{Name: "token", Value: "secret", Secure: true, HttpOnly: true},
+		{Name: "pref", Value: "dark", Domain: "example.com", Path: "/", Expires: expires},
+	})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, session.Save(&buf))
+
+	loaded, err := LoadSession(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", loaded.ID)
+	assert.Equal(t, "alice", loaded.Header.Get("X-User"))
+	cookies := loaded.Cookies("https://www.example.com/account")
+	assert.Len(t, cookies, 2)
+
+	// Cookie attributes are preserved
+	cookies = loaded.Cookies("http://www.example.com/account/x")
+	if assert.Len(t, cookies, 1, "secure cookie sent over http") {
+		assert.Equal(t, "pref", cookies[0].Name)
+	}
+	cookies = loaded.Cookies("https://shop.example.com/")
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "pref", cookies[0].Name)
+	}
+	entries := loaded.Jar.(*jar.Jar).Entries()
+	assert.Len(t, entries, 2)
+	for _, entry := range entries {
+		if entry.Name == "pref" {
+			assert.True(t, entry.Expires.Equal(expires))
+		} else {
+			assert.True(t, entry.HttpOnly)
+		}
+	}
+}
+
+func TestSessionMultiValueHeaders(t *testing.T) {
+	c := newClientDefault()
+	session := NewSession("alice")
+	session.Header["Accept-Language"] = []string{"en-US", "en;q=0.9"}
+	session.Header.Set("X-User", "alice")
+	c.AddSession(session)
+
+	req, _ := NewRequest("GET", "https://example.com", nil)
+	req.SessionID = "alice"
+	req.Header.Set("X-User", "bob")
+	c.SetSessionHeaders(req)
+	assert.Equal(t, []string{"en-US", "en;q=0.9"}, req.Header.Values("Accept-Language"))
+	assert.Equal(t, "bob", req.Header.Get("X-User"))
+}
@@ -0,0 +1,134 @@
+package dupefilter
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+const (
+	// DefaultBloomCapacity is the capacity of the first filter of Bloom
+	DefaultBloomCapacity = 100000
+	// DefaultBloomFalsePositiveRate is the default maximum false positive rate of Bloom
+	DefaultBloomFalsePositiveRate = 0.001
+
+	// bloomGrowth is the capacity ratio of each new filter to the previous one
+	bloomGrowth = 2
+	// bloomTightening is the false positive rate ratio of each new filter to the previous one
+	bloomTightening = 0.8
+)
+
+// Bloom is a scalable Bloom filter, which uses much less memory than storing fingerprints.
+// Memory isn't constant: when a filter fills up, a new filter with larger capacity and a tighter
+// false positive rate is added, so that the overall false positive rate stays under the configured rate.
+// Memory therefore grows linearly with the number of requests past the initial capacity, at a few bytes per request.
+// False positives cause unvisited requests to be considered duplicates, but it never misses a duplicate.
+type Bloom struct {
+	capacity          int
+	falsePositiveRate float64
+
+	mut     sync.Mutex
+	filters []*bloomFilter
+}
+
+// NewBloom creates a new scalable Bloom filter.
+// capacity is the expected number of requests, which is used to size the first filter.
+// falsePositiveRate is the maximum probability of considering an unvisited request visited.
+// Default values are used for non-positive parameters.
+func NewBloom(capacity int, falsePositiveRate float64) *Bloom {
+	if capacity <= 0 {
+		capacity = DefaultBloomCapacity
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = DefaultBloomFalsePositiveRate
+	}
+	return &Bloom{capacity: capacity, falsePositiveRate: falsePositiveRate}
+}
+
+// Visit marks fingerprint as visited and reports whether it's visited before
+func (b *Bloom) Visit(fingerprint string) (bool, error) {
+	h1, h2 := bloomHashes(fingerprint)
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	for _, filter := range b.filters {
+		if filter.has(h1, h2) {
+			return true, nil
+		}
+	}
+
+	last := len(b.filters) - 1
+	if last == -1 || b.filters[last].count >= b.filters[last].capacity {
+		// Filter rates form a geometric series, which sums up to falsePositiveRate
+		n := len(b.filters)
+		capacity := b.capacity * int(math.Pow(bloomGrowth, float64(n)))
+		rate := b.falsePositiveRate * (1 - bloomTightening) * math.Pow(bloomTightening, float64(n))
+		b.filters = append(b.filters, newBloomFilter(capacity, rate))
+		last++
+	}
+	b.filters[last].add(h1, h2)
+	return false, nil
+}
+
+// Size returns the memory used by filters in bytes
+func (b *Bloom) Size() int {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	var size int
+	for _, filter := range b.filters {
+		size += len(filter.bits) * 8
+	}
+	return size
+}
+
+type bloomFilter struct {
+	bits     []uint64
+	m        uint64
+	k        uint64
+	count    int
+	capacity int
+}
+
+func newBloomFilter(capacity int, falsePositiveRate float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Ceil(-math.Log2(falsePositiveRate)))
+	return &bloomFilter{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+	}
+}
+
+func (f *bloomFilter) has(h1, h2 uint64) bool {
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bloomFilter) add(h1, h2 uint64) {
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.count++
+}
+
+// bloomHashes returns two independent hashes of s, to derive k hashes using double hashing
+func bloomHashes(s string) (uint64, uint64) {
+	hash := fnv.New128a()
+	hash.Write([]byte(s))
+	sum := hash.Sum(nil)
+	var h1, h2 uint64
+	for i := 0; i < 8; i++ {
+		h1 = h1<<8 | uint64(sum[i])
+		h2 = h2<<8 | uint64(sum[i+8])
+	}
+	return h1, h2 | 1
+}
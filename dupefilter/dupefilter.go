@@ -0,0 +1,28 @@
+// Package dupefilter provides request fingerprint sets to filter duplicate requests
+package dupefilter
+
+import (
+	"sync"
+)
+
+// Filter remembers visited request fingerprints
+type Filter interface {
+	// Visit marks fingerprint as visited and reports whether it's visited before
+	Visit(fingerprint string) (visited bool, err error)
+}
+
+// Memory is an exact in-memory Filter. Memory usage grows with the number of visited requests.
+type Memory struct {
+	fingerprints sync.Map
+}
+
+// NewMemory creates a new in-memory Filter
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Visit marks fingerprint as visited and reports whether it's visited before
+func (m *Memory) Visit(fingerprint string) (bool, error) {
+	_, visited := m.fingerprints.LoadOrStore(fingerprint, struct{}{})
+	return visited, nil
+}
@@ -0,0 +1,55 @@
+package dupefilter
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testFilter(t *testing.T, filter Filter) {
+	for _, fingerprint := range []string{"a", "b", "c"} {
+		visited, err := filter.Visit(fingerprint)
+		assert.NoError(t, err)
+		assert.False(t, visited, fingerprint)
+	}
+	for _, fingerprint := range []string{"a", "b", "c"} {
+		visited, err := filter.Visit(fingerprint)
+		assert.NoError(t, err)
+		assert.True(t, visited, fingerprint)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testFilter(t, NewMemory())
+}
+
+func TestBloom(t *testing.T) {
+	testFilter(t, NewBloom(0, 0))
+}
+
+func TestBloom_Scaling(t *testing.T) {
+	const n = 20000
+	const rate = 0.01
+	bloom := NewBloom(1000, rate)
+
+	for i := 0; i < n; i++ {
+		bloom.Visit("visited-" + strconv.Itoa(i))
+	}
+	assert.Greater(t, len(bloom.filters), 1)
+
+	// Never misses a visited fingerprint
+	for i := 0; i < n; i++ {
+		visited, _ := bloom.Visit("visited-" + strconv.Itoa(i))
+		assert.True(t, visited)
+	}
+
+	// False positive rate stays under the configured rate
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if visited, _ := bloom.Visit("unvisited-" + strconv.Itoa(i)); visited {
+			falsePositives++
+		}
+	}
+	assert.Less(t, float64(falsePositives)/n, rate)
+}
@@ -0,0 +1,43 @@
+// Package leveldbfilter provides an implementation of dupefilter.Filter that
+// uses github.com/syndtr/goleveldb/leveldb, so that visited requests survive restarts
+package leveldbfilter
+
+import (
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Filter is an implementation of dupefilter.Filter with leveldb storage
+type Filter struct {
+	Db  *leveldb.DB
+	mut sync.Mutex
+}
+
+// Visit marks fingerprint as visited and reports whether it's visited before
+func (f *Filter) Visit(fingerprint string) (bool, error) {
+	key := []byte(fingerprint)
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	visited, err := f.Db.Has(key, nil)
+	if err != nil || visited {
+		return visited, err
+	}
+	return false, f.Db.Put(key, nil, nil)
+}
+
+// New returns a new Filter that will store leveldb in path
+func New(path string) (*Filter, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithDB(db), nil
+}
+
+// NewWithDB returns a new Filter using the provided leveldb as underlying storage.
+func NewWithDB(db *leveldb.DB) *Filter {
+	return &Filter{Db: db}
+}
@@ -0,0 +1,45 @@
+package leveldbfilter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "dupefilter")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "Db")
+	filter, err := New(path)
+	if err != nil {
+		t.Fatalf("New leveldb: %v", err)
+	}
+
+	visited, err := filter.Visit("a")
+	assert.NoError(t, err)
+	assert.False(t, visited)
+	visited, err = filter.Visit("a")
+	assert.NoError(t, err)
+	assert.True(t, visited)
+	filter.Db.Close()
+
+	// Survives restarts
+	filter, err = New(path)
+	if err != nil {
+		t.Fatalf("New leveldb: %v", err)
+	}
+	defer filter.Db.Close()
+	visited, err = filter.Visit("a")
+	assert.NoError(t, err)
+	assert.True(t, visited)
+	visited, err = filter.Visit("b")
+	assert.NoError(t, err)
+	assert.False(t, visited)
+}
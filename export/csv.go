@@ -1,57 +1,172 @@
 package export
 
 import (
+	"bufio"
 	"encoding/csv"
 	"fmt"
 	"github.com/geziyor/geziyor/internal"
+	"io"
 	"os"
 	"reflect"
-	"sort"
 )
 
-// CSV exports response data as CSV streaming file
+// CSV exports response data as CSV streaming file.
+// Maps and structs are written as rows of columns, with a header row.
+// Nested maps and structs are flattened, like "address.city".
+// Slices are written as rows of their values. Header row is written to each file when files are rotated.
+// Header row is written when file is opened if Fields are known, otherwise before the first row if it's a map or struct.
+// It's never written after data rows, so files starting with slice rows have no header unless Fields are set.
 type CSV struct {
 	FileName string
 	Comma    rune
 	UseCRLF  bool
+
+	// Fields are the columns, in order. Nested fields are joined with Separator.
+	// If empty, columns are discovered from the first map or struct: sorted keys of maps, field order of structs.
+	// When appending to an existing file, its header row is used.
+	Fields []string
+
+	// Separator of nested field names. Default: "."
+	Separator string
+
+	// MissingValue is written for columns missing in an item. Default: ""
+	MissingValue string
+
+	// HeaderDisabled disables writing header row
+	HeaderDisabled bool
+
+	FileOptions
 }
 
 // Export exports response data as CSV streaming file
 func (e *CSV) Export(exports chan interface{}) error {
+	out, err := newFileWriter(internal.DefaultString(e.FileName, "out.csv"), e.FileOptions)
+	if err != nil {
+		return err
+	}
+	defer out.close()
+
+	comma := internal.DefaultRune(e.Comma, ',')
+	fields := e.Fields
+	var writer *csv.Writer
+	var headerPending bool // true until the first row of file is written
+
+	writeHeader := func() error {
+		headerPending = false
+		if err := writer.Write(fields); err != nil {
+			return fmt.Errorf("writing header: %w", err)
+		}
+		return nil
+	}
+
+	out.onOpen = func(appending bool) error {
+		writer = csv.NewWriter(out)
+		writer.Comma = comma
+		writer.UseCRLF = e.UseCRLF
+		headerPending = !e.HeaderDisabled && !appending
+
+		// Use header of existing file, so that appended rows have the same columns
+		if appending && e.Compression == FileCompressionNone {
+			header, err := readCSVHeader(out.file, comma)
+			if err != nil {
+				return fmt.Errorf("reading existing header: %w", err)
+			}
+			if header == nil {
+				headerPending = !e.HeaderDisabled
+			} else if len(fields) == 0 {
+				fields = header
+			}
+		}
+
+		// Header is decided before any row if columns are known
+		if headerPending && len(fields) != 0 {
+			return writeHeader()
+		}
+		return nil
+	}
+	out.onFlush = func() error {
+		writer.Flush()
+		return writer.Error()
+	}
+	out.onClose = out.onFlush
 
 	// Create or append file
-	file, err := os.OpenFile(internal.DefaultString(e.FileName, "out.csv"), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-	if err != nil {
-		return fmt.Errorf("output file creation error: %w", err)
+	if err := out.next(); err != nil {
+		return err
 	}
-	defer file.Close()
 
-	writer := csv.NewWriter(file)
-	writer.Comma = internal.DefaultRune(e.Comma, ',')
-	writer.UseCRLF = e.UseCRLF
+	ignoredFields := make(map[string]struct{})
 
 	// Export data as responses came
-	for res := range exports {
+	err = out.exportItems(exports, func(res interface{}) error {
 		var values []string
 
 		// Detect type and extract CSV values
-		val := reflect.ValueOf(res)
+		val := indirect(reflect.ValueOf(res))
 		switch val.Kind() {
-		case reflect.Slice:
+		case reflect.Slice, reflect.Array:
 			for i := 0; i < val.Len(); i++ {
 				values = append(values, fmt.Sprint(val.Index(i)))
 			}
-		case reflect.Map:
-			for _, key := range val.MapKeys() {
-				values = append(values, fmt.Sprint(val.MapIndex(key)))
+		case reflect.Map, reflect.Struct:
+			row := make(map[string]interface{})
+			var keys []string
+			flatten(val, "", internal.DefaultString(e.Separator, "."), "csv", row, &keys, false)
+
+			if len(fields) == 0 {
+				fields = keys
+			}
+			if headerPending {
+				if err := writeHeader(); err != nil {
+					internal.Logger.Printf("CSV writing error on exporter: %v\n", err)
+				}
+			}
+
+			for _, field := range fields {
+				if value, exists := row[field]; exists {
+					values = append(values, fmt.Sprint(value))
+				} else {
+					values = append(values, e.MissingValue)
+				}
+			}
+			for _, key := range keys {
+				if _, ignored := ignoredFields[key]; !ignored && !internal.ContainsString(fields, key) {
+					ignoredFields[key] = struct{}{}
+					internal.Logger.Printf("CSV exporter ignoring field not in columns: %s\n", key)
+				}
 			}
-			sort.Strings(values)
+		default:
+			values = []string{fmt.Sprint(res)}
 		}
+		headerPending = false
 		if err := writer.Write(values); err != nil {
 			internal.Logger.Printf("CSV writing error on exporter: %v\n", err)
+			return nil
 		}
+		out.itemWritten()
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	writer.Flush()
 
+	if err := out.close(); err != nil {
+		return fmt.Errorf("file write error: %w", err)
+	}
 	return nil
 }
+
+// readCSVHeader returns the first row of file, or nil if file is empty
+func readCSVHeader(file *os.File, comma rune) ([]string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bufio.NewReader(file))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	return header, err
+}
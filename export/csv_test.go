@@ -1,7 +1,6 @@
 package export
 
 import (
-	"io/ioutil"
 	"os"
 	"testing"
 	"time"
@@ -15,15 +14,91 @@ func TestCSVExporter_Export(t *testing.T) {
 		Comma:    ';',
 	}
 	_ = os.Remove(exporter.FileName)
-	exports := make(chan interface{})
-	go exporter.Export(exports)
+	defer os.Remove(exporter.FileName)
 
-	exports <- []string{"1", "2"}
-	exports <- map[string]string{"key1": "value1", "key2": "value2"}
-	close(exports)
-	time.Sleep(time.Millisecond)
+	// Header isn't written after data rows
+	export(t, exporter,
+		[]string{"1", "2"},
+		map[string]string{"key1": "value1", "key2": "value2"},
+	)
+	assert.Equal(t, "1;2\nvalue1;value2\n", readFile(t, exporter.FileName))
+}
+
+func TestCSVExporter_Header(t *testing.T) {
+	exporter := &CSV{
+		FileName: "out.csv",
+		Comma:    ';',
+	}
+	_ = os.Remove(exporter.FileName)
+	defer os.Remove(exporter.FileName)
+
+	export(t, exporter,
+		map[string]string{"key2": "value2", "key1": "value1"},
+		map[string]string{"key1": "value3"},
+		[]string{"1", "2"},
+	)
+	assert.Equal(t, "key1;key2\nvalue1;value2\nvalue3;\n1;2\n", readFile(t, exporter.FileName))
+
+	// Appending uses header of existing file
+	export(t, exporter, map[string]string{"key2": "value4", "key1": "value5"})
+	assert.Equal(t, "key1;key2\nvalue1;value2\nvalue3;\n1;2\nvalue5;value4\n", readFile(t, exporter.FileName))
+
+	// Header of known fields is written at open, before slice rows
+	_ = os.Remove(exporter.FileName)
+	exporter.Fields = []string{"key1", "key2"}
+	export(t, exporter, []string{"1", "2"})
+	assert.Equal(t, "key1;key2\n1;2\n", readFile(t, exporter.FileName))
+}
+
+type csvAddress struct {
+	City    string `csv:"city"`
+	Country string `csv:"country"`
+}
+
+type csvBase struct {
+	ID int `csv:"id"`
+}
+
+type csvPerson struct {
+	csvBase
+	Name     string      `csv:"name"`
+	Address  csvAddress  `csv:"address"`
+	Previous *csvAddress `csv:"previous"`
+	Born     time.Time   `csv:"born"`
+	Secret   string      `csv:"-"`
+	private  string
+}
+
+func TestCSVExporter_Struct(t *testing.T) {
+	exporter := &CSV{
+		FileName:     "out.csv",
+		Separator:    "_",
+		MissingValue: "N/A",
+	}
+	_ = os.Remove(exporter.FileName)
+	defer os.Remove(exporter.FileName)
+
+	born := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	export(t, exporter,
+		csvPerson{csvBase: csvBase{1}, Name: "Ada", Address: csvAddress{"London", "UK"}, Born: born, Secret: "x", private: "y"},
+		&csvPerson{csvBase: csvBase{2}, Name: "Alan", Previous: &csvAddress{City: "Wilmslow"}, Born: born},
+	)
+	assert.Equal(t, "id,name,address_city,address_country,previous_city,previous_country,born\n"+
+		"1,Ada,London,UK,N/A,N/A,1990-01-02 00:00:00 +0000 UTC\n"+
+		"2,Alan,,,Wilmslow,,1990-01-02 00:00:00 +0000 UTC\n", readFile(t, exporter.FileName))
+}
+
+func TestCSVExporter_Fields(t *testing.T) {
+	exporter := &CSV{
+		FileName: "out.csv",
+		Fields:   []string{"name", "address.city", "age"},
+	}
+	_ = os.Remove(exporter.FileName)
+	defer os.Remove(exporter.FileName)
 
-	contents, err := ioutil.ReadFile(exporter.FileName)
-	assert.NoError(t, err)
-	assert.Equal(t, "1;2\nvalue1;value2\n", string(contents))
+	export(t, exporter,
+		map[string]interface{}{"age": 36, "name": "Ada", "address": map[string]string{"city": "London"}},
+		map[string]interface{}{"name": "Alan", "extra": true},
+	)
+	assert.Equal(t, "name,address.city,age\nAda,London,36\nAlan,,\n", readFile(t, exporter.FileName))
 }
@@ -1,7 +1,94 @@
 package export
 
+import (
+	"context"
+	"fmt"
+)
+
 // Exporter interface is for extracting data to external resources.
 // Export functions should wait for new data from exports chan.
 type Exporter interface {
 	Export(exports chan interface{}) error
 }
+
+// ExporterV2 interface is for exporters that export items one by one, so that errors are reported while scraping.
+// Open is called before scraping started, ExportItem is called for each item from a single goroutine,
+// and Close is called after all items are exported. ctx of Open is cancelled after Close.
+// Exporters can buffer items and flush them on their own schedule, but should flush them on Close.
+type ExporterV2 interface {
+	Open(ctx context.Context) error
+	ExportItem(ctx context.Context, item interface{}) error
+	Close() error
+}
+
+// AdaptExporter converts Exporter to ExporterV2.
+// Errors of Export are returned once, from ExportItem or Close. If Export returns early, next items are dropped silently.
+func AdaptExporter(e Exporter) ExporterV2 {
+	if v2, ok := e.(ExporterV2); ok {
+		return v2
+	}
+	return &exporterAdapter{exporter: e}
+}
+
+// ExporterName returns type name of exporter, without wrappers of AdaptExporter and Buffered
+func ExporterName(e ExporterV2) string {
+	switch exporter := e.(type) {
+	case *Buffered:
+		return ExporterName(exporter.ExporterV2)
+	case *exporterAdapter:
+		return fmt.Sprintf("%T", exporter.exporter)
+	}
+	return fmt.Sprintf("%T", e)
+}
+
+type exporterAdapter struct {
+	exporter Exporter
+	exports  chan interface{}
+	done     chan struct{}
+	err      error
+	reported bool
+}
+
+func (a *exporterAdapter) Open(_ context.Context) error {
+	a.exports = make(chan interface{})
+	a.done = make(chan struct{})
+	go func() {
+		defer close(a.done)
+		a.err = a.exporter.Export(a.exports)
+	}()
+	return nil
+}
+
+func (a *exporterAdapter) ExportItem(ctx context.Context, item interface{}) error {
+	select {
+	case a.exports <- item:
+		return nil
+	case <-a.done:
+		if a.err == nil {
+			a.err = fmt.Errorf("exporter stopped before exports are closed")
+		}
+		return a.terminalErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *exporterAdapter) Close() error {
+	select {
+	case <-a.done:
+		return a.terminalErr()
+	default:
+	}
+	close(a.exports)
+	<-a.done
+	return a.terminalErr()
+}
+
+// terminalErr returns error of Export only once, so that it's not reported again for each dropped item
+func (a *exporterAdapter) terminalErr() error {
+	if a.reported {
+		return nil
+	}
+	a.reported = true
+	return a.err
+}
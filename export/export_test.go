@@ -0,0 +1,50 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exporterFunc is an Exporter of a function
+type exporterFunc func(exports chan interface{}) error
+
+func (f exporterFunc) Export(exports chan interface{}) error {
+	return f(exports)
+}
+
+func TestAdaptExporter(t *testing.T) {
+	var items []interface{}
+	exporter := AdaptExporter(exporterFunc(func(exports chan interface{}) error {
+		for item := range exports {
+			items = append(items, item)
+		}
+		return errors.New("close failed")
+	}))
+	assert.NoError(t, exporter.Open(context.Background()))
+	assert.NoError(t, exporter.ExportItem(context.Background(), 1))
+	assert.NoError(t, exporter.ExportItem(context.Background(), 2))
+	assert.EqualError(t, exporter.Close(), "close failed")
+	assert.Equal(t, []interface{}{1, 2}, items)
+
+	// Exporter returning early doesn't block
+	exporter = AdaptExporter(exporterFunc(func(exports chan interface{}) error {
+		return errors.New("open failed")
+	}))
+	assert.NoError(t, exporter.Open(context.Background()))
+	assert.EqualError(t, exporter.ExportItem(context.Background(), 1), "open failed")
+
+	// Error is reported once, next items are dropped
+	assert.NoError(t, exporter.ExportItem(context.Background(), 2))
+	assert.NoError(t, exporter.Close())
+
+	exporter = AdaptExporter(exporterFunc(func(exports chan interface{}) error {
+		return nil
+	}))
+	assert.NoError(t, exporter.Open(context.Background()))
+	assert.EqualError(t, exporter.ExportItem(context.Background(), 1), "exporter stopped before exports are closed")
+	assert.NoError(t, exporter.ExportItem(context.Background(), 2))
+	assert.NoError(t, exporter.Close())
+}
@@ -0,0 +1,442 @@
+package export
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileCompression is the compression of output files
+type FileCompression string
+
+// File compressions. Only gzip is built in, FileCompressionZstd requires FileOptions.ZstdWriter.
+const (
+	FileCompressionNone FileCompression = ""
+	FileCompressionGzip FileCompression = "gzip"
+	FileCompressionZstd FileCompression = "zstd"
+)
+
+// ZstdWriter is a streaming zstd encoder, like *zstd.Encoder of github.com/klauspost/compress/zstd.
+// Geziyor doesn't include a zstd encoder, so it should be created by the user.
+// It's reset for each output file, so it shouldn't be shared between exporters.
+type ZstdWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// FileOptions are output file options of file exporters.
+// FileName of exporters can have placeholders, like "{name}-{time}-{part}.json":
+//   - {name}: Name
+//   - {time}: creation time of file, formatted with TimeFormat
+//   - {part}: part number of file, starting from 1
+//
+// If files are rotated and FileName has no {part}, it's added before extension like "out-{part}.json".
+type FileOptions struct {
+	// Name of crawl, for {name} placeholder
+	Name string
+
+	// Time format of {time} placeholder. Default: "20060102T150405"
+	TimeFormat string
+
+	// Overwrite existing files. By default, exports are appended to existing files.
+	Overwrite bool
+
+	// Compression of files. File extension (.gz, .zst) should be included in FileName.
+	Compression FileCompression
+
+	// ZstdWriter is required for FileCompressionZstd, as zstd isn't built in:
+	//
+	//	encoder, _ := zstd.NewWriter(nil)
+	ZstdWriter ZstdWriter
+
+	// Start a new file after this many items
+	MaxItems int
+
+	// Start a new file after this many bytes are written. Compressed files are checked after compression.
+	MaxSize int64
+
+	// Start a new file after this duration. It's checked when items are exported.
+	RotateInterval time.Duration
+
+	// Write directly to the output file. By default, files are written to a temporary file next to the output file,
+	// and renamed when they're completed, so that a crash doesn't leave a partial file.
+	// When appending, existing file is copied to the temporary file first.
+	AtomicDisabled bool
+
+	// Interval of flushing buffered data to file. Uncompressed JSON files are also closed
+	// at each flush, so that files written with AtomicDisabled are valid if the process is killed. Default: 1s, negative disables.
+	FlushInterval time.Duration
+}
+
+func (o *FileOptions) rotates() bool {
+	return o.MaxItems > 0 || o.MaxSize > 0 || o.RotateInterval > 0
+}
+
+// fileWriter writes exports to files of FileOptions, which are opened when needed, rotated and compressed
+type fileWriter struct {
+	options  FileOptions
+	template string
+	part     int
+
+	file       *os.File
+	fileName   string
+	counter    *countingWriter
+	compressor io.WriteCloser
+	items      int
+	opened     time.Time
+
+	// onOpen is called when a file is opened, appending is true if the file has existing content
+	onOpen func(appending bool) error
+
+	// onFlush is called when buffered data should be written to file
+	onFlush func() error
+
+	// onClose is called before the file is closed
+	onClose func() error
+}
+
+func newFileWriter(template string, options FileOptions) (*fileWriter, error) {
+	switch options.Compression {
+	case FileCompressionNone, FileCompressionGzip:
+	case FileCompressionZstd:
+		if options.ZstdWriter == nil {
+			return nil, fmt.Errorf("zstd compression requires ZstdWriter")
+		}
+	default:
+		return nil, fmt.Errorf("unknown file compression: %s", options.Compression)
+	}
+
+	if options.rotates() && !strings.Contains(template, "{part}") {
+		dir, base := filepath.Split(template)
+		if i := strings.Index(base, "."); i > 0 {
+			base = base[:i] + "-{part}" + base[i:]
+		} else {
+			base += "-{part}"
+		}
+		template = dir + base
+	}
+
+	return &fileWriter{options: options, template: template}, nil
+}
+
+// partName returns file name of current part
+func (w *fileWriter) partName() string {
+	return strings.NewReplacer(
+		"{name}", w.options.Name,
+		"{time}", w.opened.Format(fileTimeFormat(w.options.TimeFormat)),
+		"{part}", strconv.Itoa(w.part),
+	).Replace(w.template)
+}
+
+func fileTimeFormat(format string) string {
+	if format == "" {
+		return "20060102T150405"
+	}
+	return format
+}
+
+// next opens a file if there's no open file, or the open file reached rotation limits
+func (w *fileWriter) next() error {
+	if w.file != nil {
+		if !w.full() {
+			return nil
+		}
+		if err := w.close(); err != nil {
+			return err
+		}
+	}
+	return w.open()
+}
+
+func (w *fileWriter) full() bool {
+	return (w.options.MaxItems > 0 && w.items >= w.options.MaxItems) ||
+		(w.options.MaxSize > 0 && w.counter.n >= w.options.MaxSize) ||
+		(w.options.RotateInterval > 0 && time.Since(w.opened) >= w.options.RotateInterval)
+}
+
+func (w *fileWriter) open() error {
+	w.part++
+	w.items = 0
+	w.opened = time.Now()
+
+	w.fileName = w.partName()
+	path := w.fileName
+	flags := os.O_RDWR | os.O_CREATE | os.O_APPEND
+	if w.options.Overwrite {
+		flags |= os.O_TRUNC
+	}
+	if !w.options.AtomicDisabled {
+		var err error
+		if path, err = tempCopy(w.fileName, w.options.Overwrite); err != nil {
+			return fmt.Errorf("output file copy error: %w", err)
+		}
+		flags = os.O_RDWR | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0666)
+	if err != nil {
+		w.removeTemp(path)
+		return fmt.Errorf("output file creation error: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		w.removeTemp(path)
+		return fmt.Errorf("output file stat error: %w", err)
+	}
+
+	w.file = file
+	w.counter = &countingWriter{w: file, n: info.Size()}
+	switch w.options.Compression {
+	case FileCompressionGzip:
+		w.compressor = gzip.NewWriter(w.counter)
+	case FileCompressionZstd:
+		w.options.ZstdWriter.Reset(w.counter)
+		w.compressor = w.options.ZstdWriter
+	default:
+		w.compressor = nil
+	}
+
+	if w.onOpen != nil {
+		if err := w.onOpen(info.Size() != 0); err != nil {
+			w.file = nil
+			file.Close()
+			w.removeTemp(path)
+			return fmt.Errorf("output file open error: %w", err)
+		}
+	}
+	return nil
+}
+
+// removeTemp removes temporary file of atomic writes, if file is not written in place
+func (w *fileWriter) removeTemp(path string) {
+	if !w.options.AtomicDisabled {
+		os.Remove(path)
+	}
+}
+
+// Write writes p to open file, compressing if needed
+func (w *fileWriter) Write(p []byte) (int, error) {
+	if w.compressor != nil {
+		return w.compressor.Write(p)
+	}
+	return w.counter.Write(p)
+}
+
+// syncSize updates written size of open file, after it's modified without Write, like truncated
+func (w *fileWriter) syncSize() error {
+	info, err := w.file.Stat()
+	if err != nil {
+		return err
+	}
+	w.counter.n = info.Size()
+	return nil
+}
+
+// itemWritten counts items of open file, for rotation
+func (w *fileWriter) itemWritten() {
+	w.items++
+}
+
+// exportItems calls export for items of exports until it's closed, with a file opened for each item.
+// Files are flushed every FlushInterval.
+func (w *fileWriter) exportItems(exports chan interface{}, export func(item interface{}) error) error {
+	var flushTicker <-chan time.Time
+	if interval := w.options.FlushInterval; interval >= 0 {
+		ticker := time.NewTicker(fileFlushInterval(interval))
+		defer ticker.Stop()
+		flushTicker = ticker.C
+	}
+
+	for {
+		select {
+		case item, ok := <-exports:
+			if !ok {
+				return nil
+			}
+			if err := w.next(); err != nil {
+				return err
+			}
+			if err := export(item); err != nil {
+				return err
+			}
+		case <-flushTicker:
+			if err := w.flush(); err != nil {
+				return fmt.Errorf("file flush error: %w", err)
+			}
+		}
+	}
+}
+
+func fileFlushInterval(interval time.Duration) time.Duration {
+	if interval == 0 {
+		return time.Second
+	}
+	return interval
+}
+
+// flush writes buffered data of open file
+func (w *fileWriter) flush() error {
+	if w.file == nil {
+		return nil
+	}
+	if w.onFlush != nil {
+		if err := w.onFlush(); err != nil {
+			return err
+		}
+	}
+	if flusher, ok := w.compressor.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// close closes open file if any, after syncing it to disk. Atomically written files are renamed to their names.
+// It's idempotent, so that exporters can defer it in addition to calling it to check its error.
+func (w *fileWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	file := w.file
+	w.file = nil
+
+	err := w.closeFile(file)
+	if err != nil {
+		file.Close()
+		w.removeTemp(file.Name())
+		return err
+	}
+	if !w.options.AtomicDisabled {
+		if err := os.Rename(file.Name(), w.fileName); err != nil {
+			w.removeTemp(file.Name())
+			return err
+		}
+	}
+	return nil
+}
+
+// closeFile completes and closes file
+func (w *fileWriter) closeFile(file *os.File) error {
+	if w.onClose != nil {
+		if err := w.onClose(); err != nil {
+			return err
+		}
+	}
+	if w.compressor != nil {
+		if err := w.compressor.Close(); err != nil {
+			return err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// tempCopy creates a temporary file next to fileName, to write it atomically, and returns its name.
+// Existing contents of fileName are copied to it, unless truncate is true.
+func tempCopy(fileName string, truncate bool) (string, error) {
+	out, err := ioutil.TempFile(filepath.Dir(fileName), filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if err := copyExisting(out, fileName, truncate); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// atomicFile is a temporary file next to fileName, which is renamed to fileName when it's committed.
+// It's used by exporters that write whole files at the end, so that failed exports don't replace existing files.
+type atomicFile struct {
+	*os.File
+	fileName  string
+	committed bool
+}
+
+func createAtomicFile(fileName string) (*atomicFile, error) {
+	path, err := tempCopy(fileName, true)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return &atomicFile{File: file, fileName: fileName}, nil
+}
+
+// commit syncs and closes file, and renames it to its name
+func (f *atomicFile) commit() error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), f.fileName); err != nil {
+		return err
+	}
+	f.committed = true
+	return nil
+}
+
+// discard closes and removes file, unless it's committed.
+// It's idempotent, so that exporters can defer it.
+func (f *atomicFile) discard() {
+	if f.committed {
+		return
+	}
+	f.File.Close()
+	os.Remove(f.Name())
+}
+
+// copyExisting copies contents and permissions of fileName to out, if it exists.
+// Temporary files are only readable by owner, so new files get the usual permissions.
+func copyExisting(out *os.File, fileName string, truncate bool) error {
+	in, err := os.Open(fileName)
+	if os.IsNotExist(err) {
+		return out.Chmod(0644)
+	}
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := out.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	if truncate {
+		return nil
+	}
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// countingWriter counts bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,274 @@
+package export
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// export exports items with exporter, and waits for it to finish
+func export(t *testing.T, exporter Exporter, items ...interface{}) {
+	exports, wait := startExport(t, exporter)
+	for _, item := range items {
+		exports <- item
+	}
+	wait()
+}
+
+// startExport starts exporting with exporter, for checking output while exporting.
+// wait closes exports and waits for exporter to finish.
+func startExport(t *testing.T, exporter Exporter) (exports chan interface{}, wait func()) {
+	exports = make(chan interface{})
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, exporter.Export(exports))
+		close(done)
+	}()
+	return exports, func() {
+		close(exports)
+		<-done
+	}
+}
+
+func readFile(t *testing.T, fileName string) string {
+	contents, err := ioutil.ReadFile(fileName)
+	assert.NoError(t, err)
+	return string(contents)
+}
+
+func TestJSONExporter_Append(t *testing.T) {
+	exporter := &JSON{FileName: "out.json"}
+	_ = os.Remove(exporter.FileName)
+	defer os.Remove(exporter.FileName)
+
+	export(t, exporter, map[string]int{"a": 1}, map[string]int{"a": 2})
+	export(t, exporter)
+	export(t, exporter, map[string]int{"a": 3})
+	contents := readFile(t, exporter.FileName)
+	assert.Equal(t, "[\n\t{\"a\":1},\n\t{\"a\":2},\n\t{\"a\":3}\n]\n", contents)
+
+	var items []map[string]int
+	assert.NoError(t, json.Unmarshal([]byte(contents), &items))
+	assert.Len(t, items, 3)
+
+	// Files of older versions have trailing comma
+	assert.NoError(t, ioutil.WriteFile(exporter.FileName, []byte("[\n\t{\"a\":1},\n]\n"), 0666))
+	export(t, exporter, map[string]int{"a": 2})
+	assert.Equal(t, "[\n\t{\"a\":1},\n\t{\"a\":2}\n]\n", readFile(t, exporter.FileName))
+
+	// Empty array
+	assert.NoError(t, ioutil.WriteFile(exporter.FileName, []byte("[\n]\n"), 0666))
+	export(t, exporter, map[string]int{"a": 1})
+	assert.Equal(t, "[\n\t{\"a\":1}\n]\n", readFile(t, exporter.FileName))
+
+	// Overwrite
+	exporter.Overwrite = true
+	export(t, exporter, map[string]int{"a": 4})
+	assert.Equal(t, "[\n\t{\"a\":4}\n]\n", readFile(t, exporter.FileName))
+}
+
+func TestFileOptions_Rotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geziyor-export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	exporter := &CSV{
+		FileName:    filepath.Join(dir, "{name}.csv"),
+		FileOptions: FileOptions{Name: "products", MaxItems: 2},
+	}
+	export(t, exporter,
+		map[string]int{"a": 1}, map[string]int{"a": 2},
+		map[string]int{"a": 3}, map[string]int{"a": 4},
+		map[string]int{"a": 5},
+	)
+	assert.Equal(t, "a\n1\n2\n", readFile(t, filepath.Join(dir, "products-1.csv")))
+	assert.Equal(t, "a\n3\n4\n", readFile(t, filepath.Join(dir, "products-2.csv")))
+	assert.Equal(t, "a\n5\n", readFile(t, filepath.Join(dir, "products-3.csv")))
+
+	jsonExporter := &JSON{
+		FileName:    filepath.Join(dir, "part{part}.json"),
+		FileOptions: FileOptions{MaxSize: 10},
+	}
+	export(t, jsonExporter, map[string]string{"key": "value"}, map[string]string{"key": "value"})
+	assert.Equal(t, "[\n\t{\"key\":\"value\"}\n]\n", readFile(t, filepath.Join(dir, "part1.json")))
+	assert.Equal(t, "[\n\t{\"key\":\"value\"}\n]\n", readFile(t, filepath.Join(dir, "part2.json")))
+}
+
+func TestFileOptions_RotationAfterAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geziyor-export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Trailing spaces of existing file are removed, and not counted for MaxSize
+	fileName := filepath.Join(dir, "out-1.json")
+	assert.NoError(t, ioutil.WriteFile(fileName, []byte("[\n\t1\n]"+strings.Repeat(" ", 100)), 0666))
+	export(t, &JSON{FileName: filepath.Join(dir, "out.json"), FileOptions: FileOptions{MaxSize: 50}}, 2, 3)
+	assert.Equal(t, "[\n\t1,\n\t2,\n\t3\n]\n", readFile(t, fileName))
+	_, err = os.Stat(filepath.Join(dir, "out-2.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileOptions_Compression(t *testing.T) {
+	exporter := &JSONLine{
+		FileName:    "out.json.gz",
+		FileOptions: FileOptions{Compression: FileCompressionGzip, Overwrite: true},
+	}
+	defer os.Remove(exporter.FileName)
+	export(t, exporter, map[string]string{"key": "value"})
+
+	file, err := os.Open(exporter.FileName)
+	assert.NoError(t, err)
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	assert.NoError(t, err)
+	contents, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"key\":\"value\"}\n", string(contents))
+
+	assert.Error(t, (&JSON{FileOptions: FileOptions{Compression: FileCompressionZstd}}).Export(make(chan interface{})))
+	assert.Error(t, (&JSON{FileName: exporter.FileName, FileOptions: FileOptions{Compression: FileCompressionGzip}}).Export(make(chan interface{})))
+}
+
+func TestFileWriter_FileName(t *testing.T) {
+	writer, err := newFileWriter("out/{name}.csv.gz", FileOptions{Name: "quotes", RotateInterval: 1})
+	assert.NoError(t, err)
+	writer.part = 2
+	assert.Equal(t, "out/quotes-2.csv.gz", writer.partName())
+
+	writer, err = newFileWriter("{time}-{part}.json", FileOptions{TimeFormat: "2006"})
+	assert.NoError(t, err)
+	writer.part = 1
+	assert.Equal(t, "0001-1.json", writer.partName())
+}
+
+func TestFileOptions_Atomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geziyor-export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	exporter := &JSON{FileName: filepath.Join(dir, "out.json")}
+	assert.NoError(t, ioutil.WriteFile(exporter.FileName, []byte("[\n\t{\"a\":1}\n]\n"), 0640))
+
+	exports := make(chan interface{})
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, exporter.Export(exports))
+		close(done)
+	}()
+	exports <- map[string]int{"a": 2}
+
+	// Output file is not changed until export is completed
+	assert.Equal(t, "[\n\t{\"a\":1}\n]\n", readFile(t, exporter.FileName))
+	close(exports)
+	<-done
+
+	assert.Equal(t, "[\n\t{\"a\":1},\n\t{\"a\":2}\n]\n", readFile(t, exporter.FileName))
+	info, err := os.Stat(exporter.FileName)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1, "temporary files are removed")
+}
+
+func TestFileWriter_ConcurrentAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geziyor-export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Writers of the same file don't share temporary files
+	fileName := filepath.Join(dir, "out.csv")
+	first, err := newFileWriter(fileName, FileOptions{Overwrite: true})
+	assert.NoError(t, err)
+	second, err := newFileWriter(fileName, FileOptions{Overwrite: true})
+	assert.NoError(t, err)
+	assert.NoError(t, first.next())
+	assert.NoError(t, second.next())
+	assert.NotEqual(t, first.file.Name(), second.file.Name())
+
+	_, err = first.Write([]byte("first\n"))
+	assert.NoError(t, err)
+	_, err = second.Write([]byte("second\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, first.close())
+	assert.Equal(t, "first\n", readFile(t, fileName))
+	assert.NoError(t, second.close())
+	assert.Equal(t, "second\n", readFile(t, fileName))
+
+	// close is idempotent
+	assert.NoError(t, second.close())
+	assert.Equal(t, "second\n", readFile(t, fileName))
+}
+
+func TestAtomicFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geziyor-export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "out.xlsx")
+	assert.NoError(t, ioutil.WriteFile(fileName, []byte("old"), 0640))
+
+	// Discarded files don't replace existing file
+	file, err := createAtomicFile(fileName)
+	assert.NoError(t, err)
+	_, err = file.WriteString("failed")
+	assert.NoError(t, err)
+	file.discard()
+	assert.Equal(t, "old", readFile(t, fileName))
+
+	file, err = createAtomicFile(fileName)
+	assert.NoError(t, err)
+	defer file.discard()
+	_, err = file.WriteString("new")
+	assert.NoError(t, err)
+	assert.Equal(t, "old", readFile(t, fileName))
+	assert.NoError(t, file.commit())
+	file.discard()
+	assert.Equal(t, "new", readFile(t, fileName))
+
+	info, err := os.Stat(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1, "temporary files are removed")
+}
+
+func TestFileOptions_FlushInterval(t *testing.T) {
+	options := FileOptions{Overwrite: true, FlushInterval: 10 * time.Millisecond, AtomicDisabled: true}
+	jsonExporter := &JSON{FileName: "out.json", FileOptions: options}
+	csvExporter := &CSV{FileName: "out.csv", FileOptions: options}
+	defer os.Remove(jsonExporter.FileName)
+	defer os.Remove(csvExporter.FileName)
+
+	jsonExports := make(chan interface{})
+	csvExports := make(chan interface{})
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, jsonExporter.Export(jsonExports))
+		assert.NoError(t, csvExporter.Export(csvExports))
+		close(done)
+	}()
+
+	// Files are valid while exporting
+	jsonExports <- map[string]int{"a": 1}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "[\n\t{\"a\":1}\n]\n", readFile(t, jsonExporter.FileName))
+	jsonExports <- map[string]int{"a": 2}
+	close(jsonExports)
+
+	csvExports <- map[string]int{"a": 1}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "a\n1\n", readFile(t, csvExporter.FileName))
+	close(csvExports)
+	<-done
+
+	assert.Equal(t, "[\n\t{\"a\":1},\n\t{\"a\":2}\n]\n", readFile(t, jsonExporter.FileName))
+}
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"encoding"
+	"fmt"
+	"github.com/geziyor/geziyor/internal"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// flatten flattens maps and structs into row, with nested keys joined with separator.
+// Struct field names are read from tag, like `csv:"name"`. Keys are appended to keys in column order.
+// Fields of nil struct pointers are added to keys, but not to row.
+func flatten(val reflect.Value, prefix string, separator string, tag string, row map[string]interface{}, keys *[]string, missing bool) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if !val.IsNil() {
+			val = val.Elem()
+		} else if val.Kind() == reflect.Ptr {
+			val = reflect.Zero(val.Type().Elem())
+			missing = true
+		} else {
+			return
+		}
+	}
+	if !val.IsValid() {
+		return
+	}
+	if isLeaf(val) {
+		key := strings.TrimSuffix(prefix, separator)
+		if !internal.ContainsString(*keys, key) {
+			*keys = append(*keys, key)
+		}
+		if !missing {
+			row[key] = val.Interface()
+		}
+		return
+	}
+
+	switch val.Kind() {
+	case reflect.Map:
+		mapKeys := val.MapKeys()
+		sort.Slice(mapKeys, func(i, j int) bool {
+			return fmt.Sprint(mapKeys[i]) < fmt.Sprint(mapKeys[j])
+		})
+		for _, key := range mapKeys {
+			flatten(val.MapIndex(key), prefix+fmt.Sprint(key)+separator, separator, tag, row, keys, missing)
+		}
+	case reflect.Struct:
+		typ := val.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				continue
+			}
+			// Embedded structs are flattened into parent, unless named with tag
+			if field.Anonymous && name == "" && indirectType(field.Type).Kind() == reflect.Struct {
+				flatten(val.Field(i), prefix, separator, tag, row, keys, missing)
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+			flatten(val.Field(i), prefix+internal.DefaultString(name, field.Name)+separator, separator, tag, row, keys, missing)
+		}
+	}
+}
+
+// isLeaf reports whether val is exported as a single value
+func isLeaf(val reflect.Value) bool {
+	if val.Kind() != reflect.Map && val.Kind() != reflect.Struct {
+		return true
+	}
+	if val.CanInterface() {
+		switch val.Interface().(type) {
+		case fmt.Stringer, encoding.TextMarshaler:
+			return true
+		}
+	}
+	return false
+}
+
+// indirect dereferences pointers and interfaces
+func indirect(val reflect.Value) reflect.Value {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return reflect.Value{}
+		}
+		val = val.Elem()
+	}
+	return val
+}
+
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
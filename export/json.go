@@ -14,84 +14,189 @@ type JSONLine struct {
 	EscapeHTML bool
 	Prefix     string
 	Indent     string
+	FileOptions
 }
 
 // Export exports response data as JSON streaming file
 func (e *JSONLine) Export(exports chan interface{}) error {
-
-	// Create or append file
-	file, err := os.OpenFile(internal.DefaultString(e.FileName, "out.json"), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	out, err := newFileWriter(internal.DefaultString(e.FileName, "out.json"), e.FileOptions)
 	if err != nil {
-		return fmt.Errorf("output file creation error: %w", err)
+		return err
 	}
-	defer file.Close()
+	defer out.close()
 
-	encoder := json.NewEncoder(file)
+	encoder := json.NewEncoder(out)
 	encoder.SetEscapeHTML(e.EscapeHTML)
 	encoder.SetIndent(e.Prefix, e.Indent)
 
+	// Create or append file
+	if err := out.next(); err != nil {
+		return err
+	}
+
 	// Export data as responses came
-	for res := range exports {
+	err = out.exportItems(exports, func(res interface{}) error {
 		if err := encoder.Encode(res); err != nil {
 			internal.Logger.Printf("JSON encoding error on exporter: %v\n", err)
+			return nil
 		}
+		out.itemWritten()
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	if err := out.close(); err != nil {
+		return fmt.Errorf("file write error: %w", err)
+	}
 	return nil
 }
 
-// JSON exports response data as JSON
+// JSON exports response data as JSON.
+// When appending to an existing file, items are added to its array.
 type JSON struct {
 	FileName   string
 	EscapeHTML bool
+	FileOptions
 }
 
 // Export exports response data as JSON
 func (e *JSON) Export(exports chan interface{}) error {
-
-	// Create or append file
-	file, err := os.OpenFile(internal.DefaultString(e.FileName, "out.json"), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	out, err := newFileWriter(internal.DefaultString(e.FileName, "out.json"), e.FileOptions)
 	if err != nil {
-		return fmt.Errorf("output file creation error: %w", err)
+		return err
+	}
+	defer out.close()
+
+	// closed is true if closing bracket is written at flush, and should be removed before new items
+	var empty, closed bool
+	reopen := func() error {
+		var err error
+		if empty, err = reopenJSONArray(out.file); err != nil {
+			return err
+		}
+		if err := out.syncSize(); err != nil {
+			return err
+		}
+		closed = false
+		if empty {
+			_, err = out.Write([]byte("\n"))
+		}
+		return err
+	}
+	closeArray := func() error {
+		closing := "\n]\n"
+		if empty {
+			closing = "]\n"
+		}
+		_, err := out.Write([]byte(closing))
+		closed = true
+		return err
 	}
-	defer file.Close()
 
-	_, err = file.Write([]byte("[\n"))
-	if err != nil {
-		return fmt.Errorf("file write error: %w", err)
+	out.onOpen = func(appending bool) error {
+		if !appending {
+			empty, closed = true, false
+			_, err := out.Write([]byte("[\n"))
+			return err
+		}
+		if e.Compression != FileCompressionNone {
+			return fmt.Errorf("appending to compressed JSON files is not supported, use Overwrite")
+		}
+		return reopen()
+	}
+	out.onFlush = func() error {
+		// Keep uncompressed files valid between flushes
+		if e.Compression != FileCompressionNone || closed {
+			return nil
+		}
+		return closeArray()
+	}
+	out.onClose = func() error {
+		if closed {
+			return nil
+		}
+		return closeArray()
+	}
+
+	// Create or append file
+	if err := out.next(); err != nil {
+		return err
 	}
 
 	// Export data as responses came
-	for res := range exports {
+	err = out.exportItems(exports, func(res interface{}) error {
 		data, err := jsonMarshalLine(res, e.EscapeHTML)
 		if err != nil {
 			internal.Logger.Printf("JSON encoding error on exporter: %v\n", err)
-			continue
+			return nil
 		}
-		_, err = file.Write(data)
-		if err != nil {
+		if closed {
+			if err := reopen(); err != nil {
+				return fmt.Errorf("file write error: %w", err)
+			}
+		}
+		if !empty {
+			data = append([]byte(",\n"), data...)
+		}
+		if _, err := out.Write(data); err != nil {
 			return fmt.Errorf("file write error: %w", err)
 		}
+		empty = false
+		out.itemWritten()
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	_, err = file.Write([]byte("]\n"))
-	if err != nil {
+	if err := out.close(); err != nil {
 		return fmt.Errorf("file write error: %w", err)
 	}
-
 	return nil
 }
 
-// jsonMarshalLine adds tab and comma around actual data
+// jsonMarshalLine adds tab before actual data
 func jsonMarshalLine(t interface{}, escapeHTML bool) ([]byte, error) {
 	buffer := &bytes.Buffer{}
 	encoder := json.NewEncoder(buffer)
 	encoder.SetEscapeHTML(escapeHTML)
 
-	buffer.Write([]byte("	"))         // Tab char
-	err := encoder.Encode(t)          // Write actual data
+	buffer.Write([]byte("	")) // Tab char
+	if err := encoder.Encode(t); err != nil {
+		return nil, err
+	}
 	buffer.Truncate(buffer.Len() - 1) // Remove last newline char
-	buffer.Write([]byte(",\n"))       // Write comma and newline char
 
-	return buffer.Bytes(), err
+	return buffer.Bytes(), nil
+}
+
+// reopenJSONArray removes closing bracket of JSON array in file, so that items can be appended to it.
+// It returns true if the array is empty.
+func reopenJSONArray(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	size := info.Size()
+	tailSize := int64(1024)
+	if size < tailSize {
+		tailSize = size
+	}
+	tail := make([]byte, tailSize)
+	if _, err := file.ReadAt(tail, size-tailSize); err != nil {
+		return false, err
+	}
+
+	tail = bytes.TrimRight(tail, " \t\r\n")
+	if !bytes.HasSuffix(tail, []byte("]")) {
+		return false, fmt.Errorf("existing file is not a JSON array")
+	}
+	tail = bytes.TrimRight(tail[:len(tail)-1], " \t\r\n")
+	// Files of older versions have a trailing comma
+	tail = bytes.TrimSuffix(tail, []byte(","))
+	empty := bytes.HasSuffix(tail, []byte("["))
+
+	return empty, file.Truncate(size - tailSize + int64(len(tail)))
 }
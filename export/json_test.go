@@ -5,7 +5,6 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
-	"time"
 )
 
 func TestJSONLineExporter_Export(t *testing.T) {
@@ -14,12 +13,10 @@ func TestJSONLineExporter_Export(t *testing.T) {
 		Indent:   " ",
 	}
 	_ = os.Remove(exporter.FileName)
-	exports := make(chan interface{})
-	go exporter.Export(exports)
+	defer os.Remove(exporter.FileName)
 
-	exports <- map[string]string{"key": "value"}
-	close(exports)
-	time.Sleep(time.Millisecond) // Wait for writing to disk
+	// Files are renamed into place when export is completed
+	export(t, exporter, map[string]string{"key": "value"})
 
 	contents, err := ioutil.ReadFile(exporter.FileName)
 	assert.NoError(t, err)
@@ -31,12 +28,9 @@ func TestJSONExporter_Export(t *testing.T) {
 		FileName: "out.json",
 	}
 	_ = os.Remove(exporter.FileName)
-	exports := make(chan interface{})
-	go exporter.Export(exports)
+	defer os.Remove(exporter.FileName)
 
-	exports <- map[string]string{"key": "value"}
-	close(exports)
-	time.Sleep(time.Millisecond) // Wait for writing to disk
+	export(t, exporter, map[string]string{"key": "value"})
 
 	contents, err := ioutil.ReadFile(exporter.FileName)
 	assert.NoError(t, err)
@@ -0,0 +1,262 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/geziyor/geziyor/internal"
+	"github.com/golang/snappy"
+	"reflect"
+	"time"
+)
+
+// ParquetType is the type of Parquet column values
+type ParquetType int
+
+const (
+	ParquetString ParquetType = iota
+	ParquetBoolean
+	ParquetInt64
+	ParquetDouble
+	ParquetTimestamp
+	ParquetBytes
+)
+
+// ParquetColumn is a column of Parquet schema
+type ParquetColumn struct {
+	Name string
+	Type ParquetType
+}
+
+// ParquetCompression is the compression codec of Parquet pages
+type ParquetCompression string
+
+const (
+	ParquetUncompressed ParquetCompression = "none"
+	ParquetSnappy       ParquetCompression = "snappy"
+	ParquetGzip         ParquetCompression = "gzip"
+	ParquetZstd         ParquetCompression = "zstd"
+)
+
+// ZstdEncoder compresses data with zstd, like *zstd.Encoder of github.com/klauspost/compress/zstd
+type ZstdEncoder interface {
+	EncodeAll(src, dst []byte) []byte
+}
+
+// Parquet exports response data as Parquet file.
+// Maps and structs are exported, nested fields are flattened like "address_city". Struct fields can be named with `parquet` tags.
+// All columns are optional, missing fields are written as nulls and fields not in schema are ignored.
+// File is written to a temporary file next to FileName, and renamed when exports chan is closed,
+// so that existing file is replaced only if export succeeds.
+type Parquet struct {
+	// Output file. Default: out.parquet
+	FileName string
+
+	// Columns of file. If empty, columns are inferred from the first item,
+	// and no file is written if there are no items, as Parquet files can't have zero columns.
+	Schema []ParquetColumn
+
+	// Number of rows in a row group. Default: 10000
+	RowGroupSize int
+
+	// Compression codec. Default: ParquetSnappy
+	Compression ParquetCompression
+
+	// ZstdEncoder is required for ParquetZstd compression:
+	//
+	//	encoder, _ := zstd.NewWriter(nil)
+	ZstdEncoder ZstdEncoder
+
+	// Separator of nested field names. Default: "_"
+	Separator string
+}
+
+// Export exports response data as Parquet file
+func (e *Parquet) Export(exports chan interface{}) error {
+	codec, compress, err := e.compressor()
+	if err != nil {
+		return err
+	}
+
+	fileName := internal.DefaultString(e.FileName, "out.parquet")
+	file, err := createAtomicFile(fileName)
+	if err != nil {
+		return fmt.Errorf("output file creation error: %w", err)
+	}
+	defer file.discard()
+
+	writer := &parquetWriter{
+		w:        bufio.NewWriter(file),
+		columns:  e.Schema,
+		codec:    codec,
+		compress: compress,
+	}
+	if err := writer.writeMagic(); err != nil {
+		return fmt.Errorf("file write error: %w", err)
+	}
+
+	rowGroupSize := internal.DefaultInt(e.RowGroupSize, 10000)
+	ignoredFields := make(map[string]struct{})
+
+	// Export data as responses came
+	for res := range exports {
+		keys, row := e.flatten(res)
+		if len(keys) == 0 {
+			internal.Logger.Printf("Parquet exporter skipping item without fields: %v\n", res)
+			continue
+		}
+		if len(writer.columns) == 0 {
+			writer.columns = inferParquetSchema(keys, row)
+		}
+		writer.add(row)
+
+		for _, key := range keys {
+			if _, ignored := ignoredFields[key]; !ignored && !writer.hasColumn(key) {
+				ignoredFields[key] = struct{}{}
+				internal.Logger.Printf("Parquet exporter ignoring field not in schema: %s\n", key)
+			}
+		}
+
+		if writer.rows >= rowGroupSize {
+			if err := writer.writeRowGroup(); err != nil {
+				return fmt.Errorf("file write error: %w", err)
+			}
+		}
+	}
+
+	if len(writer.columns) == 0 {
+		internal.Logger.Printf("Parquet exporter has no items to infer schema, %s is not written\n", fileName)
+		return nil
+	}
+
+	if err := writer.close(); err != nil {
+		return fmt.Errorf("file write error: %w", err)
+	}
+	return file.commit()
+}
+
+func (e *Parquet) flatten(item interface{}) ([]string, map[string]interface{}) {
+	row := make(map[string]interface{})
+	var keys []string
+	val := indirect(reflect.ValueOf(item))
+	if val.Kind() == reflect.Map || val.Kind() == reflect.Struct {
+		flatten(val, "", internal.DefaultString(e.Separator, "_"), "parquet", row, &keys, false)
+	}
+	return keys, row
+}
+
+// compressor returns Parquet codec and compression function of exporter
+func (e *Parquet) compressor() (int32, func([]byte) ([]byte, error), error) {
+	switch e.Compression {
+	case ParquetUncompressed:
+		return parquetCodecUncompressed, func(data []byte) ([]byte, error) {
+			return data, nil
+		}, nil
+	case "", ParquetSnappy:
+		return parquetCodecSnappy, func(data []byte) ([]byte, error) {
+			return snappy.Encode(nil, data), nil
+		}, nil
+	case ParquetGzip:
+		return parquetCodecGzip, func(data []byte) ([]byte, error) {
+			var buffer bytes.Buffer
+			writer := gzip.NewWriter(&buffer)
+			if _, err := writer.Write(data); err != nil {
+				return nil, err
+			}
+			if err := writer.Close(); err != nil {
+				return nil, err
+			}
+			return buffer.Bytes(), nil
+		}, nil
+	case ParquetZstd:
+		if e.ZstdEncoder == nil {
+			return 0, nil, fmt.Errorf("zstd compression requires ZstdEncoder")
+		}
+		return parquetCodecZstd, func(data []byte) ([]byte, error) {
+			return e.ZstdEncoder.EncodeAll(data, nil), nil
+		}, nil
+	}
+	return 0, nil, fmt.Errorf("unknown parquet compression: %s", e.Compression)
+}
+
+// inferParquetSchema returns columns of keys, with types of values in row
+func inferParquetSchema(keys []string, row map[string]interface{}) []ParquetColumn {
+	columns := make([]ParquetColumn, len(keys))
+	for i, key := range keys {
+		columns[i] = ParquetColumn{Name: key, Type: parquetType(row[key])}
+	}
+	return columns
+}
+
+// parquetType returns Parquet column type of value
+func parquetType(value interface{}) ParquetType {
+	switch value.(type) {
+	case time.Time:
+		return ParquetTimestamp
+	case []byte:
+		return ParquetBytes
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool:
+		return ParquetBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ParquetInt64
+	case reflect.Float32, reflect.Float64:
+		return ParquetDouble
+	}
+	return ParquetString
+}
+
+// parquetValue converts value to the Go type of column type: bool, int64, float64, string or []byte.
+// Timestamps are converted to milliseconds. It returns false if value can't be converted.
+func parquetValue(typ ParquetType, value interface{}) (interface{}, bool) {
+	val := reflect.ValueOf(value)
+	switch typ {
+	case ParquetBoolean:
+		if val.Kind() == reflect.Bool {
+			return val.Bool(), true
+		}
+	case ParquetInt64:
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return val.Int(), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(val.Uint()), true
+		}
+	case ParquetDouble:
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(val.Int()), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(val.Uint()), true
+		case reflect.Float32, reflect.Float64:
+			return val.Float(), true
+		}
+	case ParquetTimestamp:
+		if t, ok := value.(time.Time); ok {
+			return t.UnixNano() / int64(time.Millisecond), true
+		}
+	case ParquetBytes:
+		if b, ok := value.([]byte); ok {
+			return b, true
+		}
+		if val.Kind() == reflect.String {
+			return []byte(val.String()), true
+		}
+	default:
+		if t, ok := value.(time.Time); ok {
+			return t.Format(time.RFC3339Nano), true
+		}
+		switch v := sqlValue(value).(type) {
+		case string:
+			return v, true
+		case []byte:
+			return string(v), true
+		default:
+			return fmt.Sprint(v), true
+		}
+	}
+	return nil, false
+}
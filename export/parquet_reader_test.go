@@ -0,0 +1,128 @@
+//go:build parquet
+// +build parquet
+
+package export
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests in this file read exported files with github.com/parquet-go/parquet-go, to check that they're readable by other implementations.
+// It isn't a dependency of the module, run these tests with:
+//
+//	go get github.com/parquet-go/parquet-go && go test -tags parquet ./export/
+
+func TestParquetExporter_Reader(t *testing.T) {
+	added := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	products := []parquetProduct{
+		{Name: "Pen", Price: 1.5, Stock: 10, InSale: true, Added: added},
+		{Name: "Book", Price: 12, Stock: 0, Added: added},
+		{Name: "Desk", Price: 99.9, Stock: 2, InSale: true, Added: added},
+	}
+	products[0].Details.Color = "blue"
+	millis := added.UnixNano() / int64(time.Millisecond)
+
+	encoder, err := zstd.NewWriter(nil)
+	assert.NoError(t, err)
+	defer os.Remove("out.parquet")
+
+	for _, compression := range []ParquetCompression{ParquetUncompressed, ParquetSnappy, ParquetGzip, ParquetZstd} {
+		exporter := &Parquet{FileName: "out.parquet", RowGroupSize: 2, Compression: compression, ZstdEncoder: encoder}
+		export(t, exporter, products[0], products[1], products[2])
+
+		columns, rows := readParquetRows(t, exporter.FileName)
+		assert.Equal(t, []string{"name", "price", "stock", "in_sale", "added", "details_color"}, columns)
+		assert.Equal(t, [][]interface{}{
+			{"Pen", 1.5, int64(10), true, millis, "blue"},
+			{"Book", 12.0, int64(0), false, millis, ""},
+			{"Desk", 99.9, int64(2), true, millis, ""},
+		}, rows, compression)
+	}
+}
+
+func TestParquetExporter_ReaderNulls(t *testing.T) {
+	exporter := &Parquet{
+		FileName: "out.parquet",
+		Schema: []ParquetColumn{
+			{Name: "title", Type: ParquetString},
+			{Name: "count", Type: ParquetInt64},
+		},
+	}
+	defer os.Remove(exporter.FileName)
+
+	export(t, exporter,
+		map[string]interface{}{"title": "a", "count": 1},
+		map[string]interface{}{"count": "invalid"},
+		map[string]interface{}{"title": 5},
+	)
+	columns, rows := readParquetRows(t, exporter.FileName)
+	assert.Equal(t, []string{"title", "count"}, columns)
+	assert.Equal(t, [][]interface{}{{"a", int64(1)}, {nil, nil}, {"5", nil}}, rows)
+
+	// Files without rows
+	export(t, exporter)
+	columns, rows = readParquetRows(t, exporter.FileName)
+	assert.Equal(t, []string{"title", "count"}, columns)
+	assert.Empty(t, rows)
+}
+
+// readParquetRows returns column names and rows of file, with values as bool, int64, float64, string or nil
+func readParquetRows(t *testing.T, fileName string) ([]string, [][]interface{}) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reader := parquet.NewReader(file)
+	defer reader.Close()
+
+	var columns []string
+	for _, path := range reader.Schema().Columns() {
+		columns = append(columns, strings.Join(path, "."))
+	}
+
+	var rows [][]interface{}
+	buffer := make([]parquet.Row, 10)
+	for {
+		n, err := reader.ReadRows(buffer)
+		for _, row := range buffer[:n] {
+			values := make([]interface{}, len(row))
+			for i, value := range row {
+				values[i] = parquetGoValue(value)
+			}
+			rows = append(rows, values)
+		}
+		if err == io.EOF {
+			return columns, rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func parquetGoValue(value parquet.Value) interface{} {
+	if value.IsNull() {
+		return nil
+	}
+	switch value.Kind() {
+	case parquet.Boolean:
+		return value.Boolean()
+	case parquet.Int64:
+		return value.Int64()
+	case parquet.Double:
+		return value.Double()
+	case parquet.ByteArray:
+		return string(value.ByteArray())
+	}
+	return value.String()
+}
@@ -0,0 +1,261 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+)
+
+type parquetProduct struct {
+	Name    string    `parquet:"name"`
+	Price   float64   `parquet:"price"`
+	Stock   int       `parquet:"stock"`
+	InSale  bool      `parquet:"in_sale"`
+	Added   time.Time `parquet:"added"`
+	Details struct {
+		Color string `parquet:"color"`
+	} `parquet:"details"`
+}
+
+func TestParquetExporter_Export(t *testing.T) {
+	added := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	products := []parquetProduct{
+		{Name: "Pen", Price: 1.5, Stock: 10, InSale: true, Added: added},
+		{Name: "Book", Price: 12, Stock: 0, Added: added},
+		{Name: "Desk", Price: 99.9, Stock: 2, InSale: true, Added: added},
+	}
+	products[0].Details.Color = "blue"
+
+	for _, compression := range []ParquetCompression{ParquetUncompressed, ParquetSnappy, ParquetGzip} {
+		exporter := &Parquet{FileName: "out.parquet", RowGroupSize: 2, Compression: compression}
+		export(t, exporter, products[0], products[1], products[2])
+
+		file := readParquetFile(t, exporter.FileName)
+		assert.Equal(t, int64(3), file.metadata[3])
+
+		var columns []string
+		for _, element := range file.metadata[2].([]interface{})[1:] {
+			columns = append(columns, string(element.(thriftStructValue)[4].([]byte)))
+		}
+		assert.Equal(t, []string{"name", "price", "stock", "in_sale", "added", "details_color"}, columns)
+
+		rowGroups := file.metadata[4].([]interface{})
+		assert.Len(t, rowGroups, 2)
+		assert.Equal(t, int64(2), rowGroups[0].(thriftStructValue)[3])
+		assert.Equal(t, int64(1), rowGroups[1].(thriftStructValue)[3])
+
+		// Pages of first row group
+		pages := file.rowGroupPages(t, 0, compression)
+		assert.Equal(t, append(parquetLevels(1, 1), "\x03\x00\x00\x00Pen\x04\x00\x00\x00Book"...), pages[0])
+		price := make([]byte, 16)
+		binary.LittleEndian.PutUint64(price, math.Float64bits(1.5))
+		binary.LittleEndian.PutUint64(price[8:], math.Float64bits(12))
+		assert.Equal(t, append(parquetLevels(1, 1), price...), pages[1])
+		assert.Equal(t, append(parquetLevels(1, 1), 0x01), pages[3])
+		millis := make([]byte, 8)
+		binary.LittleEndian.PutUint64(millis, uint64(added.UnixNano()/int64(time.Millisecond)))
+		assert.Equal(t, append(parquetLevels(1, 1), append(millis, millis...)...), pages[4])
+		assert.Equal(t, append(parquetLevels(1, 1), "\x04\x00\x00\x00blue\x00\x00\x00\x00"...), pages[5])
+	}
+	os.Remove("out.parquet")
+}
+
+func TestParquetExporter_Schema(t *testing.T) {
+	exporter := &Parquet{
+		FileName:    "out.parquet",
+		Compression: ParquetUncompressed,
+		Schema: []ParquetColumn{
+			{Name: "title", Type: ParquetString},
+			{Name: "count", Type: ParquetInt64},
+		},
+	}
+	defer os.Remove(exporter.FileName)
+
+	export(t, exporter,
+		map[string]interface{}{"title": "a", "count": 1, "ignored": true},
+		map[string]interface{}{"count": "invalid"},
+		map[string]interface{}{"title": 5},
+	)
+
+	file := readParquetFile(t, exporter.FileName)
+	assert.Equal(t, int64(3), file.metadata[3])
+	pages := file.rowGroupPages(t, 0, ParquetUncompressed)
+	assert.Equal(t, append(parquetLevels(1, 0, 1), "\x01\x00\x00\x00a\x01\x00\x00\x005"...), pages[0])
+	assert.Equal(t, append(parquetLevels(1, 0, 0), 1, 0, 0, 0, 0, 0, 0, 0), pages[1])
+}
+
+func TestParquetExporter_Empty(t *testing.T) {
+	exporter := &Parquet{FileName: "out.parquet"}
+	defer os.Remove(exporter.FileName)
+
+	export(t, exporter)
+	_, err := os.Stat(exporter.FileName)
+	assert.True(t, os.IsNotExist(err), "file without columns isn't written")
+
+	// Files with schema are written without rows
+	exporter.Schema = []ParquetColumn{{Name: "title", Type: ParquetString}}
+	export(t, exporter)
+	file := readParquetFile(t, exporter.FileName)
+	assert.Equal(t, int64(0), file.metadata[3])
+	assert.Len(t, file.metadata[2], 2)
+}
+
+func TestParquetExporter_Atomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geziyor-export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	exporter := &Parquet{FileName: filepath.Join(dir, "out.parquet")}
+	assert.NoError(t, ioutil.WriteFile(exporter.FileName, []byte("old"), 0644))
+
+	// Existing file is kept if there's nothing to write
+	export(t, exporter)
+	assert.Equal(t, "old", readFile(t, exporter.FileName))
+
+	exports, wait := startExport(t, exporter)
+	exports <- map[string]interface{}{"title": "a"}
+	assert.Equal(t, "old", readFile(t, exporter.FileName), "output file is not changed until export is completed")
+	wait()
+
+	file := readParquetFile(t, exporter.FileName)
+	assert.Equal(t, int64(1), file.metadata[3])
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1, "temporary files are removed")
+}
+
+func TestParquetExporter_Zstd(t *testing.T) {
+	exporter := &Parquet{FileName: "out.parquet", Compression: ParquetZstd}
+	assert.Error(t, exporter.Export(make(chan interface{})))
+}
+
+// parquetLevels returns expected definition levels with length prefix
+func parquetLevels(levels ...byte) []byte {
+	encoded := encodeLevels(levels)
+	return append([]byte{byte(len(encoded)), 0, 0, 0}, encoded...)
+}
+
+type parquetTestFile struct {
+	data     []byte
+	metadata thriftStructValue
+}
+
+// readParquetFile decodes metadata of file. parquet_reader_test.go checks files with another implementation.
+func readParquetFile(t *testing.T, fileName string) *parquetTestFile {
+	data, err := ioutil.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, "PAR1", string(data[:4]))
+	assert.Equal(t, "PAR1", string(data[len(data)-4:]))
+	length := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	reader := &thriftReader{data: data[len(data)-8-length : len(data)-8]}
+	metadata := reader.readStruct()
+	assert.Equal(t, length, reader.pos)
+	return &parquetTestFile{data: data, metadata: metadata}
+}
+
+// rowGroupPages returns decompressed pages of row group, by column
+func (f *parquetTestFile) rowGroupPages(t *testing.T, rowGroup int, compression ParquetCompression) [][]byte {
+	var pages [][]byte
+	chunks := f.metadata[4].([]interface{})[rowGroup].(thriftStructValue)[1].([]interface{})
+	for _, chunk := range chunks {
+		meta := chunk.(thriftStructValue)[3].(thriftStructValue)
+		offset := meta[9].(int64)
+		reader := &thriftReader{data: f.data[offset:]}
+		header := reader.readStruct()
+		assert.Equal(t, meta[7].(int64), int64(reader.pos)+int64(header[3].(int32)))
+		compressed := f.data[offset+int64(reader.pos) : offset+int64(reader.pos)+int64(header[3].(int32))]
+
+		var page []byte
+		var err error
+		switch compression {
+		case ParquetSnappy:
+			page, err = snappy.Decode(nil, compressed)
+		case ParquetGzip:
+			var gzipReader *gzip.Reader
+			gzipReader, err = gzip.NewReader(bytes.NewReader(compressed))
+			if err == nil {
+				page, err = ioutil.ReadAll(gzipReader)
+			}
+		default:
+			page = compressed
+		}
+		assert.NoError(t, err)
+		assert.Len(t, page, int(header[2].(int32)))
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+type thriftStructValue map[int16]interface{}
+
+// thriftReader decodes Thrift compact protocol, for testing
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) readStruct() thriftStructValue {
+	value := make(thriftStructValue)
+	var last int16
+	for {
+		header := r.data[r.pos]
+		r.pos++
+		if header == 0 {
+			return value
+		}
+		typ := header & 0x0f
+		if delta := int16(header >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(r.readVarint())
+		}
+		value[last] = r.readValue(typ)
+	}
+}
+
+func (r *thriftReader) readValue(typ byte) interface{} {
+	switch typ {
+	case thriftI32:
+		return int32(r.readVarint())
+	case thriftI64:
+		return r.readVarint()
+	case thriftBinary:
+		length, n := binary.Uvarint(r.data[r.pos:])
+		r.pos += n
+		value := r.data[r.pos : r.pos+int(length)]
+		r.pos += int(length)
+		return value
+	case thriftList:
+		header := r.data[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			length, n := binary.Uvarint(r.data[r.pos:])
+			r.pos += n
+			size = int(length)
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.readValue(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic("unsupported thrift type")
+}
+
+func (r *thriftReader) readVarint() int64 {
+	n, length := binary.Uvarint(r.data[r.pos:])
+	r.pos += length
+	return int64(n>>1) ^ -int64(n&1)
+}
@@ -0,0 +1,399 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"github.com/geziyor/geziyor/internal"
+	"math"
+)
+
+// Parquet format constants, from parquet.thrift of Apache Parquet
+const (
+	parquetMagic = "PAR1"
+
+	parquetTypeBoolean   = 0
+	parquetTypeInt64     = 2
+	parquetTypeDouble    = 5
+	parquetTypeByteArray = 6
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMillis = 9
+
+	parquetRepetitionOptional = 1
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetCodecUncompressed = 0
+	parquetCodecSnappy       = 1
+	parquetCodecGzip         = 2
+	parquetCodecZstd         = 6
+
+	parquetPageData = 0
+)
+
+// parquetWriter writes rows in row groups of optional columns, with a single plain encoded data page per column chunk
+type parquetWriter struct {
+	w        *bufio.Writer
+	offset   int64
+	columns  []ParquetColumn
+	codec    int32
+	compress func([]byte) ([]byte, error)
+
+	// Buffered values of current row group by column, nil values are nulls
+	values        [][]interface{}
+	rows          int
+	rowGroups     []parquetRowGroup
+	totalRows     int64
+	invalidLogged map[string]struct{}
+}
+
+type parquetRowGroup struct {
+	rows         int64
+	uncompressed int64
+	chunks       []parquetColumnChunk
+}
+
+type parquetColumnChunk struct {
+	offset       int64
+	values       int64
+	uncompressed int64
+	compressed   int64
+}
+
+func (w *parquetWriter) write(data []byte) error {
+	n, err := w.w.Write(data)
+	w.offset += int64(n)
+	return err
+}
+
+func (w *parquetWriter) writeMagic() error {
+	return w.write([]byte(parquetMagic))
+}
+
+func (w *parquetWriter) hasColumn(name string) bool {
+	for _, column := range w.columns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// add buffers values of row to current row group
+func (w *parquetWriter) add(row map[string]interface{}) {
+	if w.values == nil {
+		w.values = make([][]interface{}, len(w.columns))
+	}
+	for i, column := range w.columns {
+		var value interface{}
+		if v, exists := row[column.Name]; exists && v != nil {
+			var ok bool
+			if value, ok = parquetValue(column.Type, v); !ok {
+				w.logInvalid(column.Name, v)
+			}
+		}
+		w.values[i] = append(w.values[i], value)
+	}
+	w.rows++
+}
+
+// logInvalid logs values that can't be converted to column type, once for each column
+func (w *parquetWriter) logInvalid(column string, value interface{}) {
+	if w.invalidLogged == nil {
+		w.invalidLogged = make(map[string]struct{})
+	}
+	if _, logged := w.invalidLogged[column]; !logged {
+		w.invalidLogged[column] = struct{}{}
+		internal.Logger.Printf("Parquet exporter writing null for invalid value of column %s: %v\n", column, value)
+	}
+}
+
+// writeRowGroup writes buffered rows as a row group
+func (w *parquetWriter) writeRowGroup() error {
+	if w.rows == 0 {
+		return nil
+	}
+	rowGroup := parquetRowGroup{rows: int64(w.rows)}
+	for i, column := range w.columns {
+		chunk, err := w.writeColumnChunk(column, w.values[i])
+		if err != nil {
+			return err
+		}
+		rowGroup.uncompressed += chunk.uncompressed
+		rowGroup.chunks = append(rowGroup.chunks, chunk)
+		w.values[i] = w.values[i][:0]
+	}
+	w.rowGroups = append(w.rowGroups, rowGroup)
+	w.totalRows += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+func (w *parquetWriter) writeColumnChunk(column ParquetColumn, values []interface{}) (parquetColumnChunk, error) {
+	var levels []byte
+	var data bytes.Buffer
+	var bits []bool
+	for _, value := range values {
+		if value == nil {
+			levels = append(levels, 0)
+			continue
+		}
+		levels = append(levels, 1)
+		switch v := value.(type) {
+		case bool:
+			bits = append(bits, v)
+		case int64:
+			binary.Write(&data, binary.LittleEndian, v)
+		case float64:
+			binary.Write(&data, binary.LittleEndian, math.Float64bits(v))
+		case string:
+			binary.Write(&data, binary.LittleEndian, uint32(len(v)))
+			data.WriteString(v)
+		case []byte:
+			binary.Write(&data, binary.LittleEndian, uint32(len(v)))
+			data.Write(v)
+		}
+	}
+	if column.Type == ParquetBoolean {
+		data.Write(packBits(bits))
+	}
+
+	// Page is definition levels with length prefix and plain values, without repetition levels as columns are flat
+	encodedLevels := encodeLevels(levels)
+	page := make([]byte, 4, 4+len(encodedLevels)+data.Len())
+	binary.LittleEndian.PutUint32(page, uint32(len(encodedLevels)))
+	page = append(page, encodedLevels...)
+	page = append(page, data.Bytes()...)
+
+	compressed, err := w.compress(page)
+	if err != nil {
+		return parquetColumnChunk{}, err
+	}
+
+	header := newThriftWriter()
+	header.i32(1, parquetPageData)
+	header.i32(2, int32(len(page)))
+	header.i32(3, int32(len(compressed)))
+	header.beginStruct(5)
+	header.i32(1, int32(len(values)))
+	header.i32(2, parquetEncodingPlain)
+	header.i32(3, parquetEncodingRLE)
+	header.i32(4, parquetEncodingRLE)
+	header.endStruct()
+	header.endStruct()
+
+	chunk := parquetColumnChunk{
+		offset:       w.offset,
+		values:       int64(len(values)),
+		uncompressed: int64(header.buf.Len() + len(page)),
+		compressed:   int64(header.buf.Len() + len(compressed)),
+	}
+	if err := w.write(header.buf.Bytes()); err != nil {
+		return chunk, err
+	}
+	return chunk, w.write(compressed)
+}
+
+// close writes buffered rows and file metadata
+func (w *parquetWriter) close() error {
+	if err := w.writeRowGroup(); err != nil {
+		return err
+	}
+
+	metadata := newThriftWriter()
+	metadata.i32(1, 1)
+	metadata.list(2, thriftStruct, len(w.columns)+1)
+	metadata.beginListStruct()
+	metadata.binary(4, "schema")
+	metadata.i32(5, int32(len(w.columns)))
+	metadata.endStruct()
+	for _, column := range w.columns {
+		physical, converted := parquetPhysicalType(column.Type)
+		metadata.beginListStruct()
+		metadata.i32(1, physical)
+		metadata.i32(3, parquetRepetitionOptional)
+		metadata.binary(4, column.Name)
+		if converted >= 0 {
+			metadata.i32(6, converted)
+		}
+		metadata.endStruct()
+	}
+	metadata.i64(3, w.totalRows)
+	metadata.list(4, thriftStruct, len(w.rowGroups))
+	for _, rowGroup := range w.rowGroups {
+		metadata.beginListStruct()
+		metadata.list(1, thriftStruct, len(rowGroup.chunks))
+		for i, chunk := range rowGroup.chunks {
+			physical, _ := parquetPhysicalType(w.columns[i].Type)
+			metadata.beginListStruct()
+			metadata.i64(2, chunk.offset)
+			metadata.beginStruct(3)
+			metadata.i32(1, physical)
+			metadata.list(2, thriftI32, 2)
+			metadata.listI32(parquetEncodingPlain)
+			metadata.listI32(parquetEncodingRLE)
+			metadata.list(3, thriftBinary, 1)
+			metadata.listBinary(w.columns[i].Name)
+			metadata.i32(4, w.codec)
+			metadata.i64(5, chunk.values)
+			metadata.i64(6, chunk.uncompressed)
+			metadata.i64(7, chunk.compressed)
+			metadata.i64(9, chunk.offset)
+			metadata.endStruct()
+			metadata.endStruct()
+		}
+		metadata.i64(2, rowGroup.uncompressed)
+		metadata.i64(3, rowGroup.rows)
+		metadata.endStruct()
+	}
+	metadata.binary(6, "geziyor")
+	metadata.endStruct()
+
+	if err := w.write(metadata.buf.Bytes()); err != nil {
+		return err
+	}
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(metadata.buf.Len()))
+	if err := w.write(length); err != nil {
+		return err
+	}
+	if err := w.writeMagic(); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// parquetPhysicalType returns physical and converted types of column type. Converted type is -1 if there's none.
+func parquetPhysicalType(typ ParquetType) (int32, int32) {
+	switch typ {
+	case ParquetBoolean:
+		return parquetTypeBoolean, -1
+	case ParquetInt64:
+		return parquetTypeInt64, -1
+	case ParquetDouble:
+		return parquetTypeDouble, -1
+	case ParquetTimestamp:
+		return parquetTypeInt64, parquetConvertedTimestampMillis
+	case ParquetBytes:
+		return parquetTypeByteArray, -1
+	}
+	return parquetTypeByteArray, parquetConvertedUTF8
+}
+
+// encodeLevels encodes definition levels of bit width 1 with RLE runs of the RLE/bit-packing hybrid encoding
+func encodeLevels(levels []byte) []byte {
+	var encoded []byte
+	for i := 0; i < len(levels); {
+		run := 1
+		for i+run < len(levels) && levels[i+run] == levels[i] {
+			run++
+		}
+		encoded = appendUvarint(encoded, uint64(run)<<1)
+		encoded = append(encoded, levels[i])
+		i += run
+	}
+	return encoded
+}
+
+func appendUvarint(buf []byte, n uint64) []byte {
+	varint := make([]byte, binary.MaxVarintLen64)
+	return append(buf, varint[:binary.PutUvarint(varint, n)]...)
+}
+
+// packBits packs bools with least significant bit first
+func packBits(bits []bool) []byte {
+	packed := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with Thrift compact protocol, which is used by Parquet metadata.
+// It starts in a struct, which should be ended with endStruct.
+type thriftWriter struct {
+	buf       bytes.Buffer
+	lastField []int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{lastField: []int16{0}}
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.lastField[len(t.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	*last = id
+}
+
+// varint writes zigzag encoded n
+func (t *thriftWriter) varint(n int64) {
+	t.buf.Write(appendUvarint(nil, uint64(n<<1)^uint64(n>>63)))
+}
+
+func (t *thriftWriter) i32(id int16, n int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(n))
+}
+
+func (t *thriftWriter) i64(id int16, n int64) {
+	t.field(id, thriftI64)
+	t.varint(n)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.listBinary(s)
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginListStruct()
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0)
+	t.lastField = t.lastField[:len(t.lastField)-1]
+}
+
+// list writes list header, its elements are written with beginListStruct, listI32 and listBinary
+func (t *thriftWriter) list(id int16, elemType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.buf.Write(appendUvarint(nil, uint64(size)))
+	}
+}
+
+func (t *thriftWriter) beginListStruct() {
+	t.lastField = append(t.lastField, 0)
+}
+
+func (t *thriftWriter) listI32(n int32) {
+	t.varint(int64(n))
+}
+
+func (t *thriftWriter) listBinary(s string) {
+	t.buf.Write(appendUvarint(nil, uint64(len(s))))
+	t.buf.WriteString(s)
+}
@@ -0,0 +1,334 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/geziyor/geziyor/internal"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned when an item is dropped because exporter buffer is full
+var ErrQueueFull = errors.New("exporter buffer is full")
+
+// OverflowPolicy is the behaviour when an exporter's buffer is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for space in buffer, which slows down scraping while exporter is slow
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDrop drops items
+	OverflowDrop
+
+	// OverflowSpill writes items to a temporary file, to be exported after buffered items.
+	// Items are encoded with encoding/gob, so only their exported fields are spilled.
+	// Item types don't need to be registered, but values of interface fields, like values of map[string]interface{},
+	// should be basic types or registered with gob.Register.
+	// Items that can't be spilled are waited to be buffered after spilled items, like OverflowBlock.
+	OverflowSpill
+)
+
+// BufferOptions are options of exporter buffers, which decouple exporters from scraping and from each other
+type BufferOptions struct {
+	// Number of items buffered in memory. Default: 1000
+	Size int
+
+	// Behaviour when buffer is full. Default: OverflowBlock
+	Overflow OverflowPolicy
+
+	// Directory of spill files. Default: os.TempDir()
+	SpillDir string
+}
+
+// Buffered sets buffer options of an exporter, instead of the default ones
+type Buffered struct {
+	ExporterV2
+	BufferOptions
+}
+
+func init() {
+	// Common types of nested interface values in items, which aren't registered by gob
+	gob.Register(map[string]interface{}{})
+	gob.Register(map[string]string{})
+	gob.Register([]interface{}{})
+}
+
+// Queue is a FIFO queue of items of an exporter, with the overflow policy of BufferOptions.
+// Items are pushed from a goroutine and popped from another.
+type Queue struct {
+	options  BufferOptions
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	items    []queueItem
+	spill    *spillFile
+	spilling int // number of items being written to spill file
+	closed   bool
+}
+
+type queueItem struct {
+	Item   interface{}
+	Queued time.Time
+}
+
+// NewQueue creates a new Queue
+func NewQueue(options BufferOptions) *Queue {
+	options.Size = internal.DefaultInt(options.Size, 1000)
+	q := &Queue{options: options}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	return q
+}
+
+// Push adds item to queue, applying overflow policy if buffer is full.
+// It returns true if item is spilled to disk, and ErrQueueFull if item is dropped.
+// Items are encoded and written to disk without locking queue, so that Pop isn't blocked by spilling.
+func (q *Queue) Push(item interface{}) (bool, error) {
+	queued := queueItem{Item: item, Queued: time.Now()}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false, fmt.Errorf("queue is closed")
+	}
+
+	// Spilled items are older than the new item, so it's spilled too
+	if len(q.items) >= q.options.Size || q.spilled() != 0 || q.spilling != 0 {
+		switch q.options.Overflow {
+		case OverflowDrop:
+			return false, ErrQueueFull
+		case OverflowSpill:
+			err := q.spillItem(queued)
+			if err == nil {
+				q.notEmpty.Signal()
+				return true, nil
+			}
+			internal.Logger.Printf("Exporter buffer spill error, waiting for space: %v\n", err)
+
+			// Item is buffered after spilled items are popped, to keep the order
+			for (q.spilled() != 0 || q.spilling != 0) && !q.closed {
+				q.notFull.Wait()
+			}
+		}
+	}
+
+	for len(q.items) >= q.options.Size && !q.closed {
+		q.notFull.Wait()
+	}
+	q.items = append(q.items, queued)
+	q.notEmpty.Signal()
+	return false, nil
+}
+
+// spillItem writes item to spill file, unlocking queue while it's encoded and written
+func (q *Queue) spillItem(item queueItem) error {
+	if q.spill == nil {
+		file, err := ioutil.TempFile(q.options.SpillDir, "geziyor-export-*.spill")
+		if err != nil {
+			return err
+		}
+		q.spill = &spillFile{file: file}
+	}
+	spill := q.spill
+	q.spilling++
+	q.mu.Unlock()
+
+	err := spill.write(item)
+
+	q.mu.Lock()
+	q.spilling--
+	q.notFull.Broadcast()
+	if err != nil {
+		return err
+	}
+	if spill != q.spill {
+		return fmt.Errorf("spill file is removed after a read error")
+	}
+	spill.count++
+	return nil
+}
+
+// Pop removes the oldest item from queue, waiting for it if queue is empty.
+// It returns false when queue is closed and all items are popped.
+func (q *Queue) Pop() (interface{}, time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		// Items being spilled are waited for even if queue is closed
+		for len(q.items) == 0 && q.spilled() == 0 && (!q.closed || q.spilling != 0) {
+			q.notEmpty.Wait()
+		}
+
+		if len(q.items) != 0 {
+			item := q.items[0]
+			q.items[0] = queueItem{}
+			q.items = q.items[1:]
+			q.notFull.Broadcast()
+			return item.Item, item.Queued, true
+		}
+
+		if q.spilled() == 0 {
+			break
+		}
+		item, err := q.spill.read()
+		if err == nil {
+			q.notFull.Broadcast()
+			if q.spill.count == 0 && q.spilling == 0 {
+				// Reuse file when all items are read
+				if err := q.spill.reset(); err != nil {
+					internal.Logger.Printf("Exporter buffer spill reset error: %v\n", err)
+					q.removeSpill()
+				}
+			}
+			return item.Item, item.Queued, true
+		}
+		// Remaining spilled items can't be read after an error
+		internal.Logger.Printf("Exporter buffer spill read error, %d items lost: %v\n", q.spill.count, err)
+		q.removeSpill()
+	}
+
+	q.removeSpill()
+	return nil, time.Time{}, false
+}
+
+// Len returns number of items in queue, including spilled ones
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items) + q.spilled()
+}
+
+// Close closes queue, so that Pop returns false after remaining items are popped
+func (q *Queue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+func (q *Queue) spilled() int {
+	if q.spill == nil {
+		return 0
+	}
+	return q.spill.count
+}
+
+// removeSpill closes and removes spill file. Items being written to it are buffered in memory instead.
+func (q *Queue) removeSpill() {
+	if q.spill != nil {
+		q.spill.file.Close()
+		os.Remove(q.spill.file.Name())
+		q.spill = nil
+	}
+}
+
+// spillFile stores items as length prefixed gob messages of their type and value.
+// Types of items are kept in memory, so that they don't need to be registered with gob.
+// Items are written to writeOffset by a writer, and read from readOffset with queue locked.
+// count is the number of completely written items, which is updated with queue locked.
+type spillFile struct {
+	file       *os.File
+	readOffset int64
+	count      int
+
+	mu          sync.Mutex
+	writeOffset int64
+	types       []reflect.Type
+	typeIndexes map[reflect.Type]int
+}
+
+// spillHeader precedes item values in spill file. Type is the index of item type, or -1 for nil items.
+type spillHeader struct {
+	Type   int
+	Queued time.Time
+}
+
+func (s *spillFile) write(item queueItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buffer bytes.Buffer
+	buffer.Write(make([]byte, 4))
+	header := spillHeader{Type: -1, Queued: item.Queued}
+	if item.Item != nil {
+		header.Type = s.typeIndex(reflect.TypeOf(item.Item))
+	}
+	encoder := gob.NewEncoder(&buffer)
+	if err := encoder.Encode(&header); err != nil {
+		return err
+	}
+	if item.Item != nil {
+		if err := encoder.Encode(item.Item); err != nil {
+			return err
+		}
+	}
+	data := buffer.Bytes()
+	binary.LittleEndian.PutUint32(data, uint32(len(data)-4))
+	if _, err := s.file.WriteAt(data, s.writeOffset); err != nil {
+		return err
+	}
+	s.writeOffset += int64(len(data))
+	return nil
+}
+
+// typeIndex returns index of t in types, adding it if needed. s.mu should be locked.
+func (s *spillFile) typeIndex(t reflect.Type) int {
+	if i, exists := s.typeIndexes[t]; exists {
+		return i
+	}
+	if s.typeIndexes == nil {
+		s.typeIndexes = make(map[reflect.Type]int)
+	}
+	s.types = append(s.types, t)
+	s.typeIndexes[t] = len(s.types) - 1
+	return len(s.types) - 1
+}
+
+func (s *spillFile) read() (queueItem, error) {
+	var item queueItem
+	length := make([]byte, 4)
+	if _, err := s.file.ReadAt(length, s.readOffset); err != nil {
+		return item, err
+	}
+	data := make([]byte, binary.LittleEndian.Uint32(length))
+	if _, err := s.file.ReadAt(data, s.readOffset+4); err != nil {
+		return item, err
+	}
+
+	var header spillHeader
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&header); err != nil {
+		return item, err
+	}
+	item.Queued = header.Queued
+	if header.Type >= 0 {
+		s.mu.Lock()
+		t := s.types[header.Type]
+		s.mu.Unlock()
+		value := reflect.New(t)
+		if err := decoder.DecodeValue(value); err != nil {
+			return item, err
+		}
+		item.Item = value.Elem().Interface()
+	}
+	s.readOffset += 4 + int64(len(data))
+	s.count--
+	return item, nil
+}
+
+// reset empties file, when all items are read and no item is being written
+func (s *spillFile) reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readOffset, s.writeOffset = 0, 0
+	return s.file.Truncate(0)
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func popAll(q *Queue) []interface{} {
+	var items []interface{}
+	for {
+		item, _, ok := q.Pop()
+		if !ok {
+			return items
+		}
+		items = append(items, item)
+	}
+}
+
+func TestQueueDrop(t *testing.T) {
+	q := NewQueue(BufferOptions{Size: 2, Overflow: OverflowDrop})
+	for _, item := range []string{"a", "b", "c"} {
+		spilled, err := q.Push(item)
+		assert.False(t, spilled)
+		if item == "c" {
+			assert.Equal(t, ErrQueueFull, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+	assert.Equal(t, 2, q.Len())
+	q.Close()
+	assert.Equal(t, []interface{}{"a", "b"}, popAll(q))
+}
+
+func TestQueueBlock(t *testing.T) {
+	q := NewQueue(BufferOptions{Size: 1})
+	_, err := q.Push("a")
+	assert.NoError(t, err)
+
+	pushed := make(chan struct{})
+	go func() {
+		q.Push("b")
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("push should block while queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	item, queued, ok := q.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, "a", item)
+	assert.False(t, queued.IsZero())
+	<-pushed
+	q.Close()
+	assert.Equal(t, []interface{}{"b"}, popAll(q))
+}
+
+func TestQueueSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geziyor-queue")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	q := NewQueue(BufferOptions{Size: 2, Overflow: OverflowSpill, SpillDir: dir})
+	var spilledCount int
+	push := func(items ...interface{}) {
+		for _, item := range items {
+			spilled, err := q.Push(item)
+			assert.NoError(t, err)
+			if spilled {
+				spilledCount++
+			}
+		}
+	}
+	push("a", "b", map[string]interface{}{"c": 1}, "d")
+	assert.Equal(t, 2, spilledCount)
+	assert.Equal(t, 4, q.Len())
+
+	// Items are spilled while spill file has items, to keep the order
+	item, _, _ := q.Pop()
+	assert.Equal(t, "a", item)
+	push("e")
+	assert.Equal(t, 3, spilledCount)
+
+	q.Close()
+	assert.Equal(t, []interface{}{"b", map[string]interface{}{"c": 1}, "d", "e"}, popAll(q))
+
+	// Spill file is removed after all items are popped
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestQueueSpillStructs(t *testing.T) {
+	type product struct {
+		Name  string
+		Price float64
+	}
+
+	// Item types don't need to be registered with gob
+	q := NewQueue(BufferOptions{Size: 1, Overflow: OverflowSpill})
+	for _, item := range []interface{}{"a", product{"pen", 1.5}, &product{"book", 10}, nil} {
+		_, err := q.Push(item)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, q.spilled())
+	q.Close()
+	assert.Equal(t, []interface{}{"a", product{"pen", 1.5}, &product{"book", 10}, nil}, popAll(q))
+}
+
+func TestQueueSpillError(t *testing.T) {
+	type invalid struct{ Value func() }
+
+	q := NewQueue(BufferOptions{Size: 1, Overflow: OverflowSpill})
+	for _, item := range []string{"a", "b", "c"} {
+		q.Push(item)
+	}
+	done := make(chan struct{})
+	go func() {
+		// Item can't be spilled, so it waits for spilled items to be popped
+		spilled, err := q.Push(invalid{})
+		assert.False(t, spilled)
+		assert.NoError(t, err)
+		close(done)
+	}()
+
+	var items []interface{}
+	for i := 0; i < 4; i++ {
+		item, _, ok := q.Pop()
+		assert.True(t, ok)
+		items = append(items, item)
+	}
+	<-done
+	q.Close()
+	assert.Empty(t, popAll(q))
+	assert.Equal(t, []interface{}{"a", "b", "c", invalid{}}, items, "order is kept")
+}
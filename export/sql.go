@@ -0,0 +1,398 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/geziyor/geziyor/internal"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default values of SQL exporter
+const (
+	DefaultSQLBatchSize     = 100
+	DefaultSQLFlushInterval = 10 * time.Second
+	DefaultSQLRetryTimes    = 3
+	DefaultSQLRetryDelay    = time.Second
+)
+
+// SQLDialect is the SQL syntax of a database
+type SQLDialect interface {
+	// Quote quotes table and column names
+	Quote(identifier string) string
+
+	// Placeholder returns the placeholder of nth argument, starting from 1
+	Placeholder(n int) string
+
+	// OnConflict returns the clause that updates columns of existing rows conflicting on conflictColumns
+	OnConflict(conflictColumns []string, columns []string) string
+}
+
+// Built-in SQL dialects
+var (
+	PostgresDialect SQLDialect = postgresDialect{}
+	MySQLDialect    SQLDialect = mysqlDialect{}
+	SQLiteDialect   SQLDialect = sqliteDialect{}
+)
+
+type postgresDialect struct{}
+
+func (postgresDialect) Quote(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (d postgresDialect) OnConflict(conflictColumns []string, columns []string) string {
+	return onConflictExcluded(d, conflictColumns, columns)
+}
+
+type sqliteDialect struct{ postgresDialect }
+
+func (sqliteDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (d sqliteDialect) OnConflict(conflictColumns []string, columns []string) string {
+	return onConflictExcluded(d, conflictColumns, columns)
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Quote(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
+
+func (mysqlDialect) Placeholder(int) string {
+	return "?"
+}
+
+// OnConflict of MySQL updates rows conflicting on any unique key, conflictColumns are only excluded from updates
+func (d mysqlDialect) OnConflict(conflictColumns []string, columns []string) string {
+	var updates []string
+	for _, column := range columns {
+		if !internal.ContainsString(conflictColumns, column) {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", d.Quote(column), d.Quote(column)))
+		}
+	}
+	if len(updates) == 0 {
+		// No-op update, to ignore conflicting rows
+		updates = append(updates, fmt.Sprintf("%s = %s", d.Quote(conflictColumns[0]), d.Quote(conflictColumns[0])))
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+}
+
+// onConflictExcluded is the ON CONFLICT clause of PostgreSQL and SQLite
+func onConflictExcluded(d SQLDialect, conflictColumns []string, columns []string) string {
+	quotedConflict := make([]string, len(conflictColumns))
+	for i, column := range conflictColumns {
+		quotedConflict[i] = d.Quote(column)
+	}
+	var updates []string
+	for _, column := range columns {
+		if !internal.ContainsString(conflictColumns, column) {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", d.Quote(column), d.Quote(column)))
+		}
+	}
+	if len(updates) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(quotedConflict, ", "))
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(quotedConflict, ", "), strings.Join(updates, ", "))
+}
+
+// insertQuery returns the insert statement of rows number of rows.
+// If conflictColumns are given, conflicting rows are updated.
+func insertQuery(d SQLDialect, table string, columns []string, rows int, conflictColumns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = d.Quote(column)
+	}
+	values := make([]string, rows)
+	placeholders := make([]string, len(columns))
+	for row := 0; row < rows; row++ {
+		for i := range columns {
+			placeholders[i] = d.Placeholder(row*len(columns) + i + 1)
+		}
+		values[row] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", d.Quote(table), strings.Join(quoted, ", "), strings.Join(values, ", "))
+	if len(conflictColumns) != 0 {
+		query += " " + d.OnConflict(conflictColumns, columns)
+	}
+	return query
+}
+
+// detectDialect returns dialect of well-known database/sql driver names
+func detectDialect(driverName string) SQLDialect {
+	switch driverName {
+	case "postgres", "pgx", "cloudsqlpostgres":
+		return PostgresDialect
+	case "mysql":
+		return MySQLDialect
+	case "sqlite", "sqlite3":
+		return SQLiteDialect
+	}
+	return nil
+}
+
+// SQL exports response data to an existing database table using database/sql, in batches.
+// Maps and structs are exported, nested fields are flattened like "address_city". Struct fields can be named with `db` tags.
+// Database driver should be imported, like github.com/lib/pq, github.com/go-sql-driver/mysql or modernc.org/sqlite.
+type SQL struct {
+	// DB to export to. If nil, it's opened using DriverName and DataSourceName
+	DB             *sql.DB
+	DriverName     string
+	DataSourceName string
+
+	// Dialect of database. Default: detected from DriverName
+	Dialect SQLDialect
+
+	// Table name. Default: "items"
+	Table string
+
+	// Columns maps item fields to table columns, like {"address_city": "city"}. Other fields are not exported.
+	// Fields missing in an item are inserted as NULL, so they overwrite existing values of conflicting rows.
+	// If empty, fields of the first item are used as columns, and new fields of next items are logged and dropped.
+	Columns map[string]string
+
+	// ConflictColumns are the unique columns of table. If set, conflicting rows are updated (upsert),
+	// and only the last of the items with the same conflict values in a batch is inserted.
+	// MySQL updates rows conflicting on any unique key.
+	ConflictColumns []string
+
+	// Separator of nested field names. Default: "_"
+	Separator string
+
+	// Number of items inserted in a statement. Default: DefaultSQLBatchSize
+	BatchSize int
+
+	// Items are inserted at least this often, even if batch is not full. Default: DefaultSQLFlushInterval
+	FlushInterval time.Duration
+
+	// Maximum number of times to retry inserting a batch on transient errors. Set -1 to disable. Default: DefaultSQLRetryTimes
+	RetryTimes int
+
+	// Delay before first retry, doubled on each retry. Default: DefaultSQLRetryDelay
+	RetryDelay time.Duration
+
+	// IsTransient reports whether inserting should be retried on error. Default: IsTransientSQLError
+	IsTransient func(err error) bool
+}
+
+// Export exports response data to database table.
+// Returns the error of a batch that failed after retries, without exporting next items.
+func (e *SQL) Export(exports chan interface{}) error {
+	db := e.DB
+	if db == nil {
+		var err error
+		db, err = sql.Open(e.DriverName, e.DataSourceName)
+		if err != nil {
+			return fmt.Errorf("database open error: %w", err)
+		}
+		defer db.Close()
+	}
+	dialect := e.Dialect
+	if dialect == nil {
+		if dialect = detectDialect(e.DriverName); dialect == nil {
+			return fmt.Errorf("unknown sql dialect of driver %q", e.DriverName)
+		}
+	}
+
+	// Columns are sorted to have the same statement for all batches
+	var fields, columns []string
+	for field := range e.Columns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		columns = append(columns, e.Columns[field])
+	}
+
+	batchSize := internal.DefaultInt(e.BatchSize, DefaultSQLBatchSize)
+	var batch [][]interface{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		rows := dedupeRows(batch, columns, e.ConflictColumns)
+		batch = nil
+		var args []interface{}
+		for _, row := range rows {
+			args = append(args, row...)
+		}
+		query := insertQuery(dialect, internal.DefaultString(e.Table, "items"), columns, len(rows), e.ConflictColumns)
+		if err := e.exec(db, query, args); err != nil {
+			return fmt.Errorf("inserting %d items: %w", len(rows), err)
+		}
+		return nil
+	}
+
+	flushInterval := e.FlushInterval
+	if flushInterval == 0 {
+		flushInterval = DefaultSQLFlushInterval
+	}
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	// Fields of items that are not exported, as they're not in columns. Each is logged once.
+	var droppedFields []string
+
+	// Export data as responses came
+	for {
+		select {
+		case res, ok := <-exports:
+			if !ok {
+				return flush()
+			}
+			row := make(map[string]interface{})
+			var keys []string
+			val := indirect(reflect.ValueOf(res))
+			if val.Kind() == reflect.Map || val.Kind() == reflect.Struct {
+				flatten(val, "", internal.DefaultString(e.Separator, "_"), "db", row, &keys, false)
+			}
+			if len(keys) == 0 {
+				internal.Logger.Printf("SQL exporter skipping item without fields: %v\n", res)
+				continue
+			}
+			if fields == nil {
+				fields, columns = keys, keys
+			} else if len(e.Columns) == 0 {
+				for _, key := range keys {
+					if !internal.ContainsString(fields, key) && !internal.ContainsString(droppedFields, key) {
+						internal.Logger.Printf("SQL exporter dropping field %q, which isn't in columns of the first item\n", key)
+						droppedFields = append(droppedFields, key)
+					}
+				}
+			}
+			values := make([]interface{}, len(fields))
+			for i, field := range fields {
+				values[i] = sqlValue(row[field])
+			}
+			batch = append(batch, values)
+			if len(batch) >= batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// dedupeRows returns rows without the ones conflicting with later rows on conflictColumns, as a statement can't update a row twice.
+// Rows with NULL conflict values don't conflict.
+func dedupeRows(rows [][]interface{}, columns []string, conflictColumns []string) [][]interface{} {
+	if len(conflictColumns) == 0 {
+		return rows
+	}
+	indexes := make([]int, len(conflictColumns))
+	for i, conflictColumn := range conflictColumns {
+		if indexes[i] = indexOf(columns, conflictColumn); indexes[i] == -1 {
+			return rows
+		}
+	}
+
+	deduped := make([][]interface{}, 0, len(rows))
+	positions := make(map[string]int)
+	for _, row := range rows {
+		var key strings.Builder
+		for _, i := range indexes {
+			if row[i] == nil {
+				key.Reset()
+				break
+			}
+			fmt.Fprintf(&key, "%T:%v\x00", row[i], row[i])
+		}
+		if key.Len() == 0 {
+			deduped = append(deduped, row)
+			continue
+		}
+		if position, exists := positions[key.String()]; exists {
+			deduped[position] = row
+			continue
+		}
+		positions[key.String()] = len(deduped)
+		deduped = append(deduped, row)
+	}
+	return deduped
+}
+
+// indexOf returns index of s in slice, or -1 if it doesn't exist
+func indexOf(slice []string, s string) int {
+	for i, item := range slice {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// exec executes query, retrying on transient errors
+func (e *SQL) exec(db *sql.DB, query string, args []interface{}) error {
+	retryTimes := internal.DefaultInt(e.RetryTimes, DefaultSQLRetryTimes)
+	delay := e.RetryDelay
+	if delay == 0 {
+		delay = DefaultSQLRetryDelay
+	}
+	isTransient := e.IsTransient
+	if isTransient == nil {
+		isTransient = IsTransientSQLError
+	}
+
+	for retry := 0; ; retry++ {
+		_, err := db.Exec(query, args...)
+		if err == nil || retry >= retryTimes || !isTransient(err) {
+			return err
+		}
+		internal.Logger.Printf("Retrying SQL insert: %v\n", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// transientSQLErrors are messages of errors that can succeed on retry:
+// deadlocks, lock timeouts, serialization failures and lost connections
+var transientSQLErrors = []string{
+	"deadlock", "database is locked", "database table is locked", "lock wait timeout",
+	"could not serialize access", "sqlstate 40001", "sqlstate 40p01",
+	"connection reset", "connection refused", "broken pipe", "bad connection", "too many connections",
+}
+
+// IsTransientSQLError reports whether err is a temporary database error, that can succeed on retry
+func IsTransientSQLError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// PostgreSQL drivers expose SQLSTATE codes: serialization failures, deadlocks and connection exceptions
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		state := stateErr.SQLState()
+		if state == "40001" || state == "40P01" || strings.HasPrefix(state, "08") {
+			return true
+		}
+	}
+	message := strings.ToLower(err.Error())
+	for _, transient := range transientSQLErrors {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
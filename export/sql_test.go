@@ -0,0 +1,245 @@
+package export
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geziyor/geziyor/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver is a database/sql driver that records executed statements.
+// Queries return existing columns, for reading table info.
+// Exec returns errors in errs first, if any.
+type fakeDriver struct {
+	mut        sync.Mutex
+	statements []string
+	columns    []string
+	errs       []error
+}
+
+func (d *fakeDriver) Statements() []string {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return append([]string(nil), d.statements...)
+}
+
+func (d *fakeDriver) record(statement string) {
+	d.mut.Lock()
+	d.statements = append(d.statements, statement)
+	d.mut.Unlock()
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return &fakeTx{c.d}, nil
+}
+
+type fakeTx struct{ d *fakeDriver }
+
+func (t *fakeTx) Commit() error   { t.d.record("COMMIT"); return nil }
+func (t *fakeTx) Rollback() error { t.d.record("ROLLBACK"); return nil }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mut.Lock()
+	if len(s.d.errs) != 0 {
+		err := s.d.errs[0]
+		s.d.errs = s.d.errs[1:]
+		s.d.mut.Unlock()
+		return nil, err
+	}
+	s.d.mut.Unlock()
+	if len(args) == 0 {
+		s.d.record(s.query)
+	} else {
+		s.d.record(fmt.Sprintf("%s %v", s.query, args))
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{columns: s.d.columns}, nil
+}
+
+type fakeRows struct{ columns []string }
+
+func (r *fakeRows) Columns() []string { return []string{"name"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.columns) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.columns[0]
+	r.columns = r.columns[1:]
+	return nil
+}
+
+var fakeDriverCount int
+
+// newFakeDriver registers a new fakeDriver and returns its name
+func newFakeDriver(columns ...string) (string, *fakeDriver) {
+	fakeDriverCount++
+	name := fmt.Sprintf("fake%d", fakeDriverCount)
+	d := &fakeDriver{columns: columns}
+	sql.Register(name, d)
+	return name, d
+}
+
+// exportSQL exports items with exporter and returns its error
+func exportSQL(exporter *SQL, items ...interface{}) error {
+	exports := make(chan interface{}, len(items))
+	for _, item := range items {
+		exports <- item
+	}
+	close(exports)
+	return exporter.Export(exports)
+}
+
+func TestInsertQuery(t *testing.T) {
+	columns := []string{"id", "name"}
+	assert.Equal(t, `INSERT INTO "items" ("id", "name") VALUES ($1, $2), ($3, $4) ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name"`,
+		insertQuery(PostgresDialect, "items", columns, 2, []string{"id"}))
+	assert.Equal(t, "INSERT INTO `items` (`id`, `name`) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)",
+		insertQuery(MySQLDialect, "items", columns, 2, []string{"id"}))
+	assert.Equal(t, `INSERT INTO "items" ("id", "name") VALUES (?, ?)`,
+		insertQuery(SQLiteDialect, "items", columns, 1, nil))
+	assert.Equal(t, `INSERT INTO "items" ("id") VALUES (?) ON CONFLICT ("id") DO NOTHING`,
+		insertQuery(SQLiteDialect, "items", []string{"id"}, 1, []string{"id"}))
+}
+
+func TestSQLExporter_Export(t *testing.T) {
+	driverName, d := newFakeDriver()
+	db, err := sql.Open(driverName, "")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	err = exportSQL(&SQL{
+		DB:              db,
+		Dialect:         PostgresDialect,
+		Table:           "quotes",
+		Columns:         map[string]string{"text": "quote", "author_name": "author"},
+		ConflictColumns: []string{"quote"},
+		BatchSize:       2,
+	},
+		map[string]interface{}{"text": "a", "author": map[string]string{"name": "x"}, "ignored": 1},
+		map[string]interface{}{"text": "b"},
+		map[string]interface{}{"text": "c", "author": map[string]string{"name": "z"}},
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`INSERT INTO "quotes" ("author", "quote") VALUES ($1, $2), ($3, $4) ON CONFLICT ("quote") DO UPDATE SET "author" = excluded."author" [x a <nil> b]`,
+		`INSERT INTO "quotes" ("author", "quote") VALUES ($1, $2) ON CONFLICT ("quote") DO UPDATE SET "author" = excluded."author" [z c]`,
+	}, d.Statements())
+}
+
+func TestSQLExporter_SameBatchConflicts(t *testing.T) {
+	driverName, d := newFakeDriver()
+
+	// Only the last item of the same conflict values is inserted, in place of the first
+	err := exportSQL(&SQL{DriverName: driverName, Dialect: PostgresDialect, ConflictColumns: []string{"id"}},
+		map[string]interface{}{"id": 1, "name": "a"},
+		map[string]interface{}{"id": 2, "name": "b"},
+		map[string]interface{}{"id": 1, "name": "c"},
+		map[string]interface{}{"id": nil, "name": "d"},
+		map[string]interface{}{"id": nil, "name": "e"},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`INSERT INTO "items" ("id", "name") VALUES ($1, $2), ($3, $4), ($5, $6), ($7, $8) ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name" [1 c 2 b <nil> d <nil> e]`,
+	}, d.Statements())
+}
+
+func TestSQLExporter_Retry(t *testing.T) {
+	driverName, d := newFakeDriver()
+	d.errs = []error{errors.New("Error 1213: Deadlock found when trying to get lock"), errors.New("syntax error")}
+
+	err := exportSQL(&SQL{DriverName: driverName, Dialect: MySQLDialect, RetryDelay: time.Millisecond},
+		map[string]interface{}{"id": 1},
+		map[string]interface{}{"id": 2},
+	)
+	// Retried after deadlock, but not after syntax error, which is returned
+	assert.EqualError(t, err, "inserting 2 items: syntax error")
+	assert.Empty(t, d.Statements())
+
+	d.errs = []error{errors.New("database is locked")}
+	err = exportSQL(&SQL{DriverName: driverName, Dialect: SQLiteDialect, RetryDelay: time.Millisecond},
+		map[string]interface{}{"id": 1},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"INSERT INTO \"items\" (\"id\") VALUES (?) [1]"}, d.Statements())
+}
+
+func TestSQLExporter_FlushInterval(t *testing.T) {
+	driverName, d := newFakeDriver()
+	exports, wait := startExport(t, &SQL{DriverName: driverName, Dialect: SQLiteDialect, FlushInterval: 10 * time.Millisecond})
+
+	exports <- map[string]interface{}{"id": 1}
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, d.Statements(), 1)
+	wait()
+}
+
+func TestIsTransientSQLError(t *testing.T) {
+	assert.True(t, IsTransientSQLError(driver.ErrBadConn))
+	assert.True(t, IsTransientSQLError(fmt.Errorf("insert: %w", errors.New("ERROR: deadlock detected (SQLSTATE 40P01)"))))
+	assert.False(t, IsTransientSQLError(errors.New("duplicate key value violates unique constraint")))
+}
+
+func TestSQLExporter_SQLite(t *testing.T) {
+	db, driverName := openSQLite(t, ":memory:")
+	defer db.Close()
+	// Every connection of :memory: is a new database
+	db.SetMaxOpenConns(1)
+	_, err := db.Exec(`CREATE TABLE quotes (quote TEXT PRIMARY KEY, author TEXT)`)
+	assert.NoError(t, err)
+
+	var logs bytes.Buffer
+	defer internal.Logger.SetOutput(internal.Logger.Writer())
+	internal.Logger.SetOutput(&logs)
+
+	err = exportSQL(&SQL{DB: db, DriverName: driverName, Table: "quotes", ConflictColumns: []string{"quote"}, BatchSize: 2},
+		map[string]interface{}{"quote": "a", "author": "x"},
+		map[string]interface{}{"quote": "b", "author": "y", "tags": []string{"t"}},
+		map[string]interface{}{"quote": "a", "author": "z", "tags": []string{"t"}},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(logs.String(), `dropping field "tags"`))
+
+	rows, err := db.Query(`SELECT quote, author FROM quotes ORDER BY quote`)
+	assert.NoError(t, err)
+	var got []string
+	for rows.Next() {
+		var quote, author string
+		assert.NoError(t, rows.Scan(&quote, &author))
+		got = append(got, quote+"="+author)
+	}
+	assert.NoError(t, rows.Close())
+	assert.Equal(t, []string{"a=z", "b=y"}, got)
+
+	// Batches failing after retries are returned
+	err = exportSQL(&SQL{DB: db, DriverName: driverName, Table: "quotes", RetryTimes: -1},
+		map[string]interface{}{"quote": "b", "author": "y"},
+	)
+	assert.Error(t, err)
+}
@@ -0,0 +1,283 @@
+package export
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/geziyor/geziyor/internal"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrNoSQLiteDriver is returned when no SQLite database/sql driver is registered
+var ErrNoSQLiteDriver = errors.New(`no sqlite driver registered, import one like _ "modernc.org/sqlite" or _ "github.com/mattn/go-sqlite3"`)
+
+// SQLite exports response data to a SQLite database table.
+// Table is created from the first item's fields or from Schema, and new columns are added for new fields.
+// Maps and structs are exported, nested fields are flattened like "address_city". Struct fields can be named with `db` tags.
+//
+// A database/sql SQLite driver should be imported, like the pure-Go modernc.org/sqlite:
+//
+//	import _ "modernc.org/sqlite"
+type SQLite struct {
+	// Database file. Default: out.db
+	FileName string
+
+	// Registered database/sql driver name. Default: "sqlite" or "sqlite3", whichever is registered
+	DriverName string
+
+	// Table name. Default: "items"
+	Table string
+
+	// Primary key column. If set, items with existing primary keys are updated (upsert)
+	PrimaryKey string
+
+	// Number of items inserted in a transaction. Default: 100
+	BatchSize int
+
+	// Schema is an optional struct or map value, whose fields are used to create table before exporting
+	Schema interface{}
+
+	// Separator of nested field names. Default: "_"
+	Separator string
+}
+
+// NewSQLite creates a SQLite exporter of fileName, using the registered SQLite driver.
+// Returns ErrNoSQLiteDriver if no driver is imported.
+func NewSQLite(fileName string) (*SQLite, error) {
+	driverName, err := sqliteDriverName()
+	if err != nil {
+		return nil, err
+	}
+	return &SQLite{FileName: fileName, DriverName: driverName}, nil
+}
+
+// sqliteDriverName returns the name of registered SQLite driver
+func sqliteDriverName() (string, error) {
+	drivers := sql.Drivers()
+	for _, name := range []string{"sqlite", "sqlite3"} {
+		if internal.ContainsString(drivers, name) {
+			return name, nil
+		}
+	}
+	return "", ErrNoSQLiteDriver
+}
+
+// Export exports response data to SQLite database.
+// Returns the first error of creating table or inserting items, without exporting next items.
+func (e *SQLite) Export(exports chan interface{}) error {
+	driverName := e.DriverName
+	if driverName == "" {
+		var err error
+		if driverName, err = sqliteDriverName(); err != nil {
+			return err
+		}
+	}
+	db, err := sql.Open(driverName, internal.DefaultString(e.FileName, "out.db"))
+	if err != nil {
+		return fmt.Errorf("database open error: %w", err)
+	}
+	defer db.Close()
+
+	table := &sqliteTable{
+		db:         db,
+		name:       internal.DefaultString(e.Table, "items"),
+		primaryKey: e.PrimaryKey,
+	}
+	if err := table.loadColumns(); err != nil {
+		return fmt.Errorf("reading table columns: %w", err)
+	}
+	if e.Schema != nil {
+		keys, row := e.flatten(e.Schema)
+		if err := table.addColumns(keys, row); err != nil {
+			return fmt.Errorf("creating table: %w", err)
+		}
+	}
+
+	batchSize := internal.DefaultInt(e.BatchSize, 100)
+	batch := make([]sqlRow, 0, batchSize)
+	flush := func() error {
+		defer func() { batch = batch[:0] }()
+		if err := table.insert(batch); err != nil {
+			return fmt.Errorf("inserting %d items: %w", len(batch), err)
+		}
+		return nil
+	}
+
+	// Export data as responses came
+	for res := range exports {
+		keys, row := e.flatten(res)
+		if len(keys) == 0 {
+			internal.Logger.Printf("SQLite exporter skipping item without fields: %v\n", res)
+			continue
+		}
+		if err := table.addColumns(keys, row); err != nil {
+			return fmt.Errorf("altering table: %w", err)
+		}
+		batch = append(batch, sqlRow{keys: keys, values: row})
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) != 0 {
+		return flush()
+	}
+
+	return nil
+}
+
+func (e *SQLite) flatten(item interface{}) ([]string, map[string]interface{}) {
+	row := make(map[string]interface{})
+	var keys []string
+	val := indirect(reflect.ValueOf(item))
+	if val.Kind() == reflect.Map || val.Kind() == reflect.Struct {
+		flatten(val, "", internal.DefaultString(e.Separator, "_"), "db", row, &keys, false)
+	}
+	return keys, row
+}
+
+// sqlRow is a flattened item, with keys in column order
+type sqlRow struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// sqliteTable creates and alters table for items, and inserts them
+type sqliteTable struct {
+	db         *sql.DB
+	name       string
+	primaryKey string
+	columns    []string
+}
+
+// loadColumns reads columns of table if it exists
+func (t *sqliteTable) loadColumns() error {
+	rows, err := t.db.Query("SELECT name FROM pragma_table_info(?)", t.name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return err
+		}
+		t.columns = append(t.columns, column)
+	}
+	return rows.Err()
+}
+
+// addColumns creates table, or adds columns of keys that don't exist in table
+func (t *sqliteTable) addColumns(keys []string, row map[string]interface{}) error {
+	if t.columns == nil {
+		var definitions []string
+		if t.primaryKey != "" && !internal.ContainsString(keys, t.primaryKey) {
+			definitions = append(definitions, SQLiteDialect.Quote(t.primaryKey)+" TEXT PRIMARY KEY")
+			t.columns = append(t.columns, t.primaryKey)
+		}
+		for _, key := range keys {
+			definition := SQLiteDialect.Quote(key) + " " + sqliteType(row[key])
+			if key == t.primaryKey {
+				definition += " PRIMARY KEY"
+			}
+			definitions = append(definitions, definition)
+			t.columns = append(t.columns, key)
+		}
+		_, err := t.db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", SQLiteDialect.Quote(t.name), strings.Join(definitions, ", ")))
+		if err != nil {
+			t.columns = nil
+		}
+		return err
+	}
+
+	for _, key := range keys {
+		if internal.ContainsString(t.columns, key) {
+			continue
+		}
+		_, err := t.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", SQLiteDialect.Quote(t.name), SQLiteDialect.Quote(key), sqliteType(row[key])))
+		if err != nil {
+			return err
+		}
+		t.columns = append(t.columns, key)
+	}
+	return nil
+}
+
+// insert inserts rows in a transaction
+func (t *sqliteTable) insert(rows []sqlRow) error {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		args := make([]interface{}, len(row.keys))
+		for i, key := range row.keys {
+			args[i] = sqlValue(row.values[key])
+		}
+		var conflictColumns []string
+		if t.primaryKey != "" && internal.ContainsString(row.keys, t.primaryKey) {
+			conflictColumns = []string{t.primaryKey}
+		}
+		if _, err := tx.Exec(insertQuery(SQLiteDialect, t.name, row.keys, 1, conflictColumns), args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// sqliteType returns SQLite column type of value
+func sqliteType(value interface{}) string {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
+	}
+	if _, ok := value.([]byte); ok {
+		return "BLOB"
+	}
+	return "TEXT"
+}
+
+// sqlValue converts value to a database/sql driver value
+func sqlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, []byte, bool, time.Time:
+		return v
+	case driver.Valuer:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case encoding.TextMarshaler:
+		if text, err := v.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	val := reflect.ValueOf(value)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		return val.Float()
+	case reflect.Bool:
+		return val.Bool()
+	case reflect.String:
+		return val.String()
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
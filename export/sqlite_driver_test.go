@@ -0,0 +1,8 @@
+//go:build sqlite
+// +build sqlite
+
+package export
+
+// Tests with a real SQLite database use the pure-Go driver, so that cgo isn't needed.
+// It isn't a dependency of the module, see openSQLite.
+import _ "modernc.org/sqlite"
@@ -0,0 +1,165 @@
+package export
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/geziyor/geziyor/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+type sqliteProduct struct {
+	ID    int     `db:"id"`
+	Name  string  `db:"name"`
+	Price float64 `db:"price"`
+	Stock struct {
+		Count int `db:"count"`
+	} `db:"stock"`
+}
+
+func TestSQLiteExporter_Export(t *testing.T) {
+	driverName, d := newFakeDriver()
+	exporter := &SQLite{
+		DriverName: driverName,
+		Table:      "products",
+		PrimaryKey: "id",
+		BatchSize:  2,
+	}
+	product := sqliteProduct{ID: 1, Name: "pen", Price: 1.5}
+	product.Stock.Count = 10
+	export(t, exporter,
+		map[string]interface{}{"id": 1, "name": "pen"},
+		map[string]interface{}{"id": 2, "name": "book", "tags": []string{"a", "b"}},
+		product,
+	)
+
+	assert.Equal(t, []string{
+		`CREATE TABLE IF NOT EXISTS "products" ("id" INTEGER PRIMARY KEY, "name" TEXT)`,
+		`ALTER TABLE "products" ADD COLUMN "tags" TEXT`,
+		"BEGIN",
+		`INSERT INTO "products" ("id", "name") VALUES (?, ?) ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name" [1 pen]`,
+		`INSERT INTO "products" ("id", "name", "tags") VALUES (?, ?, ?) ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name", "tags" = excluded."tags" [2 book ["a","b"]]`,
+		"COMMIT",
+		`ALTER TABLE "products" ADD COLUMN "price" REAL`,
+		`ALTER TABLE "products" ADD COLUMN "stock_count" INTEGER`,
+		"BEGIN",
+		`INSERT INTO "products" ("id", "name", "price", "stock_count") VALUES (?, ?, ?, ?) ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name", "price" = excluded."price", "stock_count" = excluded."stock_count" [1 pen 1.5 10]`,
+		"COMMIT",
+	}, d.statements)
+}
+
+func TestSQLiteExporter_ExistingTable(t *testing.T) {
+	driverName, d := newFakeDriver("id", "name")
+	exporter := &SQLite{DriverName: driverName, Schema: sqliteProduct{}}
+	exports := make(chan interface{})
+	close(exports)
+	assert.NoError(t, exporter.Export(exports))
+
+	assert.Equal(t, []string{
+		`ALTER TABLE "items" ADD COLUMN "price" REAL`,
+		`ALTER TABLE "items" ADD COLUMN "stock_count" INTEGER`,
+	}, d.statements)
+	assert.False(t, strings.Contains(strings.Join(d.statements, "\n"), "CREATE"))
+}
+
+func TestSQLiteExporter_InsertError(t *testing.T) {
+	driverName, d := newFakeDriver("id")
+	insertErr := errors.New("constraint failed")
+	d.errs = []error{insertErr}
+
+	exports := make(chan interface{}, 2)
+	exports <- map[string]interface{}{"id": 1}
+	exports <- map[string]interface{}{"id": 2}
+	close(exports)
+	err := (&SQLite{DriverName: driverName, BatchSize: 1}).Export(exports)
+	assert.ErrorIs(t, err, insertErr)
+	assert.Len(t, exports, 1, "items after the error are not exported")
+}
+
+// openSQLite opens a SQLite database with the registered driver, skipping test if no driver is registered.
+// SQLite driver isn't a dependency of the module, run these tests with:
+//
+//	go get modernc.org/sqlite && go test -tags sqlite ./export/
+func openSQLite(t *testing.T, dataSourceName string) (*sql.DB, string) {
+	driverName, err := sqliteDriverName()
+	if err != nil {
+		t.Skip(err)
+	}
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, driverName
+}
+
+func TestNewSQLite(t *testing.T) {
+	exporter, err := NewSQLite("out.db")
+	switch {
+	case internal.ContainsString(sql.Drivers(), "sqlite"):
+		assert.NoError(t, err)
+		assert.Equal(t, "sqlite", exporter.DriverName)
+	case internal.ContainsString(sql.Drivers(), "sqlite3"):
+		assert.NoError(t, err)
+		assert.Equal(t, "sqlite3", exporter.DriverName)
+	default:
+		assert.ErrorIs(t, err, ErrNoSQLiteDriver)
+	}
+}
+
+func TestSQLiteExporter_SQLite(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sqlite")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	fileName := filepath.Join(tempDir, "items.db")
+
+	db, _ := openSQLite(t, fileName)
+	defer db.Close()
+
+	exportProducts := func(items ...interface{}) error {
+		exports := make(chan interface{}, len(items))
+		for _, item := range items {
+			exports <- item
+		}
+		close(exports)
+		return (&SQLite{FileName: fileName, Table: "products", PrimaryKey: "id", BatchSize: 2}).Export(exports)
+	}
+
+	product := sqliteProduct{ID: 1, Name: "pen", Price: 1.5}
+	product.Stock.Count = 10
+	assert.NoError(t, exportProducts(
+		map[string]interface{}{"id": 1, "name": "pencil"},
+		map[string]interface{}{"id": 2, "name": "book", "tags": []string{"a", "b"}},
+		product,
+	))
+	// Table is evolved on the next run
+	assert.NoError(t, exportProducts(map[string]interface{}{"id": 3, "name": "bag", "color": "red"}))
+
+	rows, err := db.Query(`SELECT id, name, price, stock_count, tags, color FROM products ORDER BY id`)
+	assert.NoError(t, err)
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id int
+		var name string
+		var price sql.NullFloat64
+		var count sql.NullInt64
+		var tags, color sql.NullString
+		assert.NoError(t, rows.Scan(&id, &name, &price, &count, &tags, &color))
+		got = append(got, fmt.Sprintf("%d|%s|%v|%d|%s|%s", id, name, price.Float64, count.Int64, tags.String, color.String))
+	}
+	assert.NoError(t, rows.Err())
+	assert.Equal(t, []string{
+		"1|pen|1.5|10||",
+		`2|book|0|0|["a","b"]|`,
+		"3|bag|0|0||red",
+	}, got)
+
+	// Failed inserts are returned
+	assert.Error(t, exportProducts(map[string]interface{}{"id": []int{1}, "name": "invalid"}))
+}
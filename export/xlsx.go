@@ -0,0 +1,531 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"github.com/geziyor/geziyor/internal"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Excel limits
+const (
+	xlsxMaxSheetName  = 31
+	xlsxMaxCellText   = 32767
+	xlsxMaxHyperlinks = 65530
+	xlsxMaxExactInt   = 1e15
+)
+
+// xlsxMaxRows is the maximum number of rows of a sheet, variable for tests
+var xlsxMaxRows = 1048576
+
+// Cell styles, indexes of cellXfs in xlsxStyles
+const (
+	xlsxStyleDefault = iota
+	xlsxStyleHeader
+	xlsxStyleDate
+	xlsxStyleHyperlink
+)
+
+// XLSX exports response data as Excel XLSX file.
+// Maps and structs are written as rows with a bold header row, nested fields are flattened like "address.city".
+// Struct fields can be named with `xlsx` tags. Slices are written as rows of their values.
+// Numbers, booleans and times are written as typed cells, and http(s) URLs as hyperlinks.
+// Rows are streamed to temporary files, and the workbook is written when exports chan is closed,
+// to a temporary file next to FileName which is renamed on success, so that existing file is replaced only if export succeeds.
+// When a sheet reaches the Excel limit of 1,048,576 rows, rows continue on a new sheet like "Sheet1 (2)".
+type XLSX struct {
+	// Output file. Default: out.xlsx
+	FileName string
+
+	// Sheet name of items. Default: "Sheet1"
+	Sheet string
+
+	// SheetField is the item field whose value is the sheet name of item, like "category".
+	// Items without the field are written to Sheet.
+	SheetField string
+
+	// Fields are the columns, in order. Nested fields are joined with Separator.
+	// If empty, columns of each sheet are discovered from its first map or struct.
+	Fields []string
+
+	// Separator of nested field names. Default: "."
+	Separator string
+
+	// Maximum column width in characters. Default: 60
+	MaxColumnWidth int
+}
+
+// Export exports response data as Excel XLSX file
+func (e *XLSX) Export(exports chan interface{}) error {
+	file, err := createAtomicFile(internal.DefaultString(e.FileName, "out.xlsx"))
+	if err != nil {
+		return fmt.Errorf("output file creation error: %w", err)
+	}
+	defer file.discard()
+
+	var sheets []*xlsxSheet
+	sheetsByName := make(map[string]*xlsxSheet)
+	defer func() {
+		for _, sheet := range sheets {
+			sheet.remove()
+		}
+	}()
+	getSheet := func(name string) (*xlsxSheet, error) {
+		name = xlsxSheetName(name)
+		current, exists := sheetsByName[strings.ToLower(name)]
+		if exists && !current.full() {
+			return current, nil
+		}
+
+		// Full sheets are continued on a new sheet with the same columns
+		sheetName, columns := name, e.Fields
+		if exists {
+			columns = current.columns
+			for n := 2; ; n++ {
+				sheetName = xlsxContinuedSheetName(name, n)
+				if _, taken := sheetsByName[strings.ToLower(sheetName)]; !taken {
+					break
+				}
+			}
+		}
+		sheet, err := newXLSXSheet(sheetName, columns)
+		if err != nil {
+			return nil, fmt.Errorf("temporary file creation error: %w", err)
+		}
+		sheets = append(sheets, sheet)
+		sheetsByName[strings.ToLower(name)] = sheet
+		sheetsByName[strings.ToLower(sheetName)] = sheet
+		return sheet, nil
+	}
+
+	defaultSheet := internal.DefaultString(e.Sheet, "Sheet1")
+	ignoredFields := make(map[string]struct{})
+
+	// Export data as responses came
+	for res := range exports {
+		var values []interface{}
+		sheetName := defaultSheet
+
+		// Detect type and extract cell values
+		val := indirect(reflect.ValueOf(res))
+		switch val.Kind() {
+		case reflect.Slice, reflect.Array:
+			if _, isBytes := val.Interface().([]byte); !isBytes {
+				for i := 0; i < val.Len(); i++ {
+					values = append(values, val.Index(i).Interface())
+				}
+				break
+			}
+			values = []interface{}{res}
+		case reflect.Map, reflect.Struct:
+			if isLeaf(val) {
+				values = []interface{}{res}
+				break
+			}
+			row := make(map[string]interface{})
+			var keys []string
+			flatten(val, "", internal.DefaultString(e.Separator, "."), "xlsx", row, &keys, false)
+
+			if value, exists := row[e.SheetField]; e.SheetField != "" && exists && fmt.Sprint(value) != "" {
+				sheetName = fmt.Sprint(value)
+			}
+			sheet, err := getSheet(sheetName)
+			if err != nil {
+				return err
+			}
+			if sheet.columns == nil {
+				sheet.columns = keys
+			}
+			for _, column := range sheet.columns {
+				values = append(values, row[column])
+			}
+			for _, key := range keys {
+				if _, ignored := ignoredFields[key]; !ignored && !internal.ContainsString(sheet.columns, key) {
+					ignoredFields[key] = struct{}{}
+					internal.Logger.Printf("XLSX exporter ignoring field not in columns: %s\n", key)
+				}
+			}
+		default:
+			values = []interface{}{res}
+		}
+
+		sheet, err := getSheet(sheetName)
+		if err != nil {
+			return err
+		}
+		if err := sheet.writeRow(values); err != nil {
+			return fmt.Errorf("temporary file write error: %w", err)
+		}
+	}
+
+	// Workbook needs at least a sheet
+	if len(sheets) == 0 {
+		if _, err := getSheet(defaultSheet); err != nil {
+			return err
+		}
+	}
+
+	if err := e.writeWorkbook(file, sheets); err != nil {
+		return fmt.Errorf("file write error: %w", err)
+	}
+	return file.commit()
+}
+
+// writeWorkbook writes XLSX package of sheets to w
+func (e *XLSX) writeWorkbook(w io.Writer, sheets []*xlsxSheet) error {
+	archive := zip.NewWriter(w)
+
+	var contentTypes, workbook, workbookRels strings.Builder
+	for i, sheet := range sheets {
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.name), i+1, i+1)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", fmt.Sprintf(xlsxContentTypes, contentTypes.String())},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, workbook.String())},
+		{"xl/_rels/workbook.xml.rels", fmt.Sprintf(xlsxRelationships, workbookRels.String())},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		writer, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(writer, xml.Header+part.content); err != nil {
+			return err
+		}
+	}
+
+	for i, sheet := range sheets {
+		writer, err := archive.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := sheet.writeTo(writer, e.MaxColumnWidth); err != nil {
+			return err
+		}
+		if len(sheet.hyperlinks) != 0 {
+			writer, err := archive.Create(fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", i+1))
+			if err != nil {
+				return err
+			}
+			var rels strings.Builder
+			for j, hyperlink := range sheet.hyperlinks {
+				fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="%s" TargetMode="External"/>`, j+1, xmlEscape(hyperlink.url))
+			}
+			if _, err := io.WriteString(writer, xml.Header+fmt.Sprintf(xlsxRelationships, rels.String())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return archive.Close()
+}
+
+// xlsxSheet is a worksheet, whose rows are written to a temporary file
+type xlsxSheet struct {
+	name       string
+	columns    []string
+	file       *os.File
+	w          *bufio.Writer
+	rows       int
+	widths     []int
+	hyperlinks []xlsxHyperlink
+}
+
+type xlsxHyperlink struct {
+	ref string
+	url string
+}
+
+func newXLSXSheet(name string, columns []string) (*xlsxSheet, error) {
+	file, err := ioutil.TempFile("", "geziyor-xlsx-*.xml")
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxSheet{name: name, columns: columns, file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// writeRow writes values as a row, after header row of sheet columns
+func (s *xlsxSheet) writeRow(values []interface{}) error {
+	if s.rows == 0 && len(s.columns) != 0 {
+		header := make([]interface{}, len(s.columns))
+		for i, column := range s.columns {
+			header[i] = column
+		}
+		if err := s.writeCells(header, xlsxStyleHeader); err != nil {
+			return err
+		}
+	}
+	return s.writeCells(values, xlsxStyleDefault)
+}
+
+// full reports whether sheet can't have another row, including its header row
+func (s *xlsxSheet) full() bool {
+	if s.rows == 0 && len(s.columns) != 0 {
+		return xlsxMaxRows < 2
+	}
+	return s.rows >= xlsxMaxRows
+}
+
+func (s *xlsxSheet) writeCells(values []interface{}, style int) error {
+	if s.rows >= xlsxMaxRows {
+		return fmt.Errorf("sheet %s exceeds %d rows", s.name, xlsxMaxRows)
+	}
+	s.rows++
+	fmt.Fprintf(s.w, `<row r="%d">`, s.rows)
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		ref := xlsxColumnName(i) + strconv.Itoa(s.rows)
+		text, width := s.writeCell(ref, value, style)
+		if i >= len(s.widths) {
+			s.widths = append(s.widths, make([]int, i+1-len(s.widths))...)
+		}
+		if width == 0 {
+			width = utf8.RuneCountInString(text)
+		}
+		if width > s.widths[i] {
+			s.widths[i] = width
+		}
+	}
+	_, err := s.w.WriteString("</row>")
+	return err
+}
+
+// writeCell writes typed cell of value, and returns its text and width if it's different from text length
+func (s *xlsxSheet) writeCell(ref string, value interface{}, style int) (string, int) {
+	if t, ok := value.(time.Time); ok {
+		if serial, ok := xlsxDate(t); ok {
+			fmt.Fprintf(s.w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDate, strconv.FormatFloat(serial, 'f', -1, 64))
+			return "", len("2006-01-02 15:04:05")
+		}
+	}
+
+	val := reflect.ValueOf(value)
+	var number string
+	switch val.Kind() {
+	case reflect.Bool:
+		bit := 0
+		if val.Bool() {
+			bit = 1
+		}
+		fmt.Fprintf(s.w, `<c r="%s" t="b"><v>%d</v></c>`, ref, bit)
+		return strings.ToUpper(strconv.FormatBool(val.Bool())), 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if math.Abs(float64(val.Int())) < xlsxMaxExactInt {
+			number = strconv.FormatInt(val.Int(), 10)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if float64(val.Uint()) < xlsxMaxExactInt {
+			number = strconv.FormatUint(val.Uint(), 10)
+		}
+	case reflect.Float32, reflect.Float64:
+		if !math.IsNaN(val.Float()) && !math.IsInf(val.Float(), 0) {
+			number = strconv.FormatFloat(val.Float(), 'g', -1, 64)
+		}
+	}
+	if number != "" {
+		fmt.Fprintf(s.w, `<c r="%s"><v>%s</v></c>`, ref, number)
+		return number, 0
+	}
+
+	// Strings and other values are written as inline strings
+	var text string
+	switch v := sqlValue(value).(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		text = fmt.Sprint(v)
+	}
+	if utf8.RuneCountInString(text) > xlsxMaxCellText {
+		text = string([]rune(text)[:xlsxMaxCellText])
+	}
+	if style == xlsxStyleDefault && len(s.hyperlinks) < xlsxMaxHyperlinks && isHyperlink(text) {
+		s.hyperlinks = append(s.hyperlinks, xlsxHyperlink{ref: ref, url: text})
+		style = xlsxStyleHyperlink
+	}
+	fmt.Fprintf(s.w, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlEscape(text))
+	return text, 0
+}
+
+// writeTo writes worksheet to w, with column widths, rows and hyperlinks
+func (s *xlsxSheet) writeTo(w io.Writer, maxWidth int) error {
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+
+	var header strings.Builder
+	header.WriteString(xml.Header)
+	header.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	if len(s.columns) != 0 {
+		// Freeze header row
+		header.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	if len(s.widths) != 0 {
+		header.WriteString("<cols>")
+		for i, width := range s.widths {
+			fmt.Fprintf(&header, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, xlsxColumnWidth(width, maxWidth))
+		}
+		header.WriteString("</cols>")
+	}
+	header.WriteString("<sheetData>")
+	if _, err := io.WriteString(w, header.String()); err != nil {
+		return err
+	}
+
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, s.file); err != nil {
+		return err
+	}
+
+	var footer strings.Builder
+	footer.WriteString("</sheetData>")
+	if len(s.hyperlinks) != 0 {
+		footer.WriteString("<hyperlinks>")
+		for i, hyperlink := range s.hyperlinks {
+			fmt.Fprintf(&footer, `<hyperlink ref="%s" r:id="rId%d"/>`, hyperlink.ref, i+1)
+		}
+		footer.WriteString("</hyperlinks>")
+	}
+	footer.WriteString("</worksheet>")
+	_, err := io.WriteString(w, footer.String())
+	return err
+}
+
+// remove closes and removes temporary file of sheet
+func (s *xlsxSheet) remove() {
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
+
+// xlsxColumnWidth returns column width of content width, with padding
+func xlsxColumnWidth(width int, maxWidth int) int {
+	width += 2
+	if width < 10 {
+		width = 10
+	}
+	if maxWidth = internal.DefaultInt(maxWidth, 60); width > maxWidth {
+		width = maxWidth
+	}
+	return width
+}
+
+// xlsxColumnName returns column name of zero based index, like A, B, ..., Z, AA
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxSheetName returns valid sheet name of name, without invalid characters and shortened to 31 characters
+func xlsxSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, "'")
+	if utf8.RuneCountInString(name) > xlsxMaxSheetName {
+		name = string([]rune(name)[:xlsxMaxSheetName])
+	}
+	return internal.DefaultString(name, "Sheet")
+}
+
+// xlsxContinuedSheetName returns the name of nth sheet continuing sheet name, like "Sheet1 (2)"
+func xlsxContinuedSheetName(name string, n int) string {
+	suffix := fmt.Sprintf(" (%d)", n)
+	if max := xlsxMaxSheetName - len(suffix); utf8.RuneCountInString(name) > max {
+		name = string([]rune(name)[:max])
+	}
+	return name + suffix
+}
+
+// xlsxDate returns Excel serial date of t, which is days since 1899-12-30.
+// Dates before 1900-03-01 aren't supported, as Excel treats 1900 as a leap year.
+func xlsxDate(t time.Time) (float64, bool) {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	if wall.Before(time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)) || wall.Year() > 9999 {
+		return 0, false
+	}
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	seconds := float64(wall.Unix()-epoch.Unix()) + float64(wall.Nanosecond())/1e9
+	return seconds / (24 * 60 * 60), true
+}
+
+// isHyperlink reports whether text is an absolute http(s) URL
+func isHyperlink(text string) bool {
+	if (!strings.HasPrefix(text, "http://") && !strings.HasPrefix(text, "https://")) || strings.ContainsAny(text, " \t\n") {
+		return false
+	}
+	u, err := url.Parse(text)
+	return err == nil && u.Host != ""
+}
+
+func xmlEscape(s string) string {
+	var builder strings.Builder
+	xml.EscapeText(&builder, []byte(s))
+	return builder.String()
+}
+
+const xlsxContentTypes = `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`%s</Types>`
+
+const xlsxRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxRelationships = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">%s</Relationships>`
+
+const xlsxWorkbook = `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets>%s</sheets></workbook>`
+
+const xlsxStyles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="3">` +
+	`<font><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><b/><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><u/><sz val="11"/><color rgb="FF0563C1"/><name val="Calibri"/></font>` +
+	`</fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="2" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
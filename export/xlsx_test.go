@@ -0,0 +1,168 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type xlsxProduct struct {
+	Name     string    `xlsx:"name"`
+	Category string    `xlsx:"category"`
+	Price    float64   `xlsx:"price"`
+	InSale   bool      `xlsx:"in_sale"`
+	URL      string    `xlsx:"url"`
+	Added    time.Time `xlsx:"added"`
+}
+
+func TestXLSXExporter_Export(t *testing.T) {
+	exporter := &XLSX{FileName: "out.xlsx", SheetField: "category"}
+	defer os.Remove(exporter.FileName)
+
+	added := time.Date(2022, 1, 2, 12, 0, 0, 0, time.UTC)
+	exports := make(chan interface{})
+	go func() {
+		exports <- xlsxProduct{Name: "Pen & Paper", Category: "office", Price: 1.5, InSale: true, URL: "https://example.com/pen?a=1&b=2", Added: added}
+		exports <- xlsxProduct{Name: "Novel", Category: "books/fiction", Price: 12, URL: "not a url"}
+		exports <- map[string]interface{}{"name": "Desk", "category": "office", "extra": 1}
+		exports <- []int{1, 2}
+		close(exports)
+	}()
+	assert.NoError(t, exporter.Export(exports))
+
+	parts := readXLSXParts(t, exporter.FileName)
+	assert.Contains(t, parts["xl/workbook.xml"], `<sheet name="office" sheetId="1" r:id="rId1"/><sheet name="books_fiction" sheetId="2" r:id="rId2"/><sheet name="Sheet1" sheetId="3" r:id="rId3"/>`)
+
+	office := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, office, `<row r="1"><c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">name</t></is></c>`)
+	assert.Contains(t, office, `<c r="A2" s="0" t="inlineStr"><is><t xml:space="preserve">Pen &amp; Paper</t></is></c>`)
+	assert.Contains(t, office, `<c r="C2"><v>1.5</v></c><c r="D2" t="b"><v>1</v></c>`)
+	assert.Contains(t, office, `<c r="E2" s="3" t="inlineStr"><is><t xml:space="preserve">https://example.com/pen?a=1&amp;b=2</t></is></c>`)
+	assert.Contains(t, office, `<c r="F2" s="2"><v>44563.5</v></c>`)
+	assert.Contains(t, office, `<row r="3"><c r="A3" s="0" t="inlineStr"><is><t xml:space="preserve">Desk</t></is></c><c r="B3" s="0" t="inlineStr"><is><t xml:space="preserve">office</t></is></c></row>`)
+	assert.Contains(t, office, `<hyperlinks><hyperlink ref="E2" r:id="rId1"/></hyperlinks>`)
+	assert.Contains(t, office, `<col min="1" max="1" width="13" customWidth="1"/>`)
+	assert.Contains(t, parts["xl/worksheets/_rels/sheet1.xml.rels"], `Target="https://example.com/pen?a=1&amp;b=2" TargetMode="External"`)
+
+	books := parts["xl/worksheets/sheet2.xml"]
+	assert.Contains(t, books, `<c r="E2" s="0" t="inlineStr"><is><t xml:space="preserve">not a url</t></is></c>`)
+	assert.NotContains(t, books, "<hyperlinks>")
+	_, exists := parts["xl/worksheets/_rels/sheet2.xml.rels"]
+	assert.False(t, exists)
+
+	assert.Contains(t, parts["xl/worksheets/sheet3.xml"], `<sheetData><row r="1"><c r="A1"><v>1</v></c><c r="B1"><v>2</v></c></row></sheetData>`)
+}
+
+func TestXLSXExporter_Empty(t *testing.T) {
+	exporter := &XLSX{FileName: "out.xlsx", Fields: []string{"name"}}
+	defer os.Remove(exporter.FileName)
+
+	exports := make(chan interface{})
+	close(exports)
+	assert.NoError(t, exporter.Export(exports))
+
+	parts := readXLSXParts(t, exporter.FileName)
+	assert.Contains(t, parts["xl/workbook.xml"], `<sheet name="Sheet1" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(t, parts["xl/worksheets/sheet1.xml"], "<sheetData></sheetData>")
+}
+
+func TestXLSXExporter_Atomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geziyor-export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	exporter := &XLSX{FileName: filepath.Join(dir, "out.xlsx")}
+	assert.NoError(t, ioutil.WriteFile(exporter.FileName, []byte("old"), 0644))
+
+	exports, wait := startExport(t, exporter)
+	exports <- map[string]interface{}{"name": "Pen"}
+	assert.Equal(t, "old", readFile(t, exporter.FileName), "output file is not changed until export is completed")
+	wait()
+
+	parts := readXLSXParts(t, exporter.FileName)
+	assert.Contains(t, parts["xl/worksheets/sheet1.xml"], "Pen")
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1, "temporary files are removed")
+}
+
+func TestXLSXExporter_MaxRows(t *testing.T) {
+	defer func(maxRows int) { xlsxMaxRows = maxRows }(xlsxMaxRows)
+	xlsxMaxRows = 3
+
+	exporter := &XLSX{FileName: "out.xlsx"}
+	defer os.Remove(exporter.FileName)
+
+	exports := make(chan interface{}, 5)
+	for i := 1; i <= 5; i++ {
+		exports <- map[string]interface{}{"id": i}
+	}
+	close(exports)
+	assert.NoError(t, exporter.Export(exports))
+
+	parts := readXLSXParts(t, exporter.FileName)
+	assert.Contains(t, parts["xl/workbook.xml"], `<sheet name="Sheet1" sheetId="1" r:id="rId1"/><sheet name="Sheet1 (2)" sheetId="2" r:id="rId2"/><sheet name="Sheet1 (3)" sheetId="3" r:id="rId3"/>`)
+	for sheet, ids := range map[string][]string{"sheet1": {"1", "2"}, "sheet2": {"3", "4"}, "sheet3": {"5"}} {
+		content := parts["xl/worksheets/"+sheet+".xml"]
+		assert.Contains(t, content, `<t xml:space="preserve">id</t>`, "header row of "+sheet)
+		assert.Equal(t, len(ids)+1, strings.Count(content, "<row "))
+		for _, id := range ids {
+			assert.Contains(t, content, "<v>"+id+"</v>")
+		}
+	}
+}
+
+func TestXLSXHelpers(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumnName(0))
+	assert.Equal(t, "Z", xlsxColumnName(25))
+	assert.Equal(t, "AA", xlsxColumnName(26))
+	assert.Equal(t, "ZZ", xlsxColumnName(701))
+	assert.Equal(t, "AAA", xlsxColumnName(702))
+
+	assert.Equal(t, "a_b_c", xlsxSheetName("a/b:c"))
+	assert.Equal(t, strings.Repeat("x", 31), xlsxSheetName(strings.Repeat("x", 40)))
+	assert.Equal(t, strings.Repeat("x", 27)+" (2)", xlsxContinuedSheetName(strings.Repeat("x", 31), 2))
+
+	serial, ok := xlsxDate(time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, float64(61), serial)
+	_, ok = xlsxDate(time.Date(1899, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
+
+// readXLSXParts returns contents of package parts, and checks that they're well-formed
+func readXLSXParts(t *testing.T, fileName string) map[string]string {
+	archive, err := zip.OpenReader(fileName)
+	assert.NoError(t, err)
+	defer archive.Close()
+
+	parts := make(map[string]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		assert.NoError(t, err)
+		content, err := ioutil.ReadAll(reader)
+		assert.NoError(t, err)
+		reader.Close()
+		parts[file.Name] = string(content)
+
+		decoder := xml.NewDecoder(strings.NewReader(string(content)))
+		for {
+			if _, err := decoder.Token(); err != nil {
+				assert.Equal(t, io.EOF, err, file.Name)
+				break
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		assert.Contains(t, parts, name)
+	}
+	return parts
+}
@@ -0,0 +1,205 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/geziyor/geziyor/internal"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// XML exports response data as XML streaming file, like <items><item>...</item></items>.
+// Structs are encoded using encoding/xml, so `xml` struct tags are supported.
+// Maps are encoded as elements named by their keys, and slices as repeated elements.
+// Files are always overwritten, as appending would make the document invalid.
+// Uncompressed files are closed on each flush, so that they're valid while exporting.
+type XML struct {
+	FileName string
+
+	// Name of root element. Default: "items"
+	RootElement string
+
+	// Name of item elements. Default: "item"
+	ItemElement string
+
+	Prefix string
+	Indent string
+	FileOptions
+}
+
+// Export exports response data as XML streaming file
+func (e *XML) Export(exports chan interface{}) error {
+	options := e.FileOptions
+	options.Overwrite = true
+	out, err := newFileWriter(internal.DefaultString(e.FileName, "out.xml"), options)
+	if err != nil {
+		return err
+	}
+	defer out.close()
+
+	root := internal.DefaultString(e.RootElement, "items")
+	indenting := e.Prefix != "" || e.Indent != ""
+	closing := "</" + root + ">\n"
+	if indenting {
+		closing = "\n" + e.Prefix + closing
+	}
+
+	// closed is true if closing tag is written at flush, and should be removed before new items
+	var closed bool
+	reopen := func() error {
+		info, err := out.file.Stat()
+		if err != nil {
+			return err
+		}
+		if err := out.file.Truncate(info.Size() - int64(len(closing))); err != nil {
+			return err
+		}
+		closed = false
+		return out.syncSize()
+	}
+	closeRoot := func() error {
+		_, err := io.WriteString(out, closing)
+		closed = true
+		return err
+	}
+
+	out.onOpen = func(bool) error {
+		closed = false
+		_, err := io.WriteString(out, xml.Header+e.Prefix+"<"+root+">")
+		return err
+	}
+	out.onFlush = func() error {
+		// Keep uncompressed files valid between flushes
+		if e.Compression != FileCompressionNone || closed {
+			return nil
+		}
+		return closeRoot()
+	}
+	out.onClose = func() error {
+		if closed {
+			return nil
+		}
+		return closeRoot()
+	}
+
+	// Create file
+	if err := out.next(); err != nil {
+		return err
+	}
+
+	// Export data as responses came
+	itemElement := internal.DefaultString(e.ItemElement, "item")
+	var buf bytes.Buffer
+	err = out.exportItems(exports, func(res interface{}) error {
+		// Items are encoded to buffer first, so that failing items don't leave unclosed elements in file
+		buf.Reset()
+		if indenting {
+			buf.WriteString("\n")
+		}
+		encoder := xml.NewEncoder(&buf)
+		encoder.Indent(e.Prefix+e.Indent, e.Indent)
+		if err := encodeXML(encoder, itemElement, reflect.ValueOf(res)); err != nil {
+			internal.Logger.Printf("XML encoding error on exporter: %v\n", err)
+			return nil
+		}
+		if err := encoder.Flush(); err != nil {
+			internal.Logger.Printf("XML encoding error on exporter: %v\n", err)
+			return nil
+		}
+		if closed {
+			if err := reopen(); err != nil {
+				return fmt.Errorf("file write error: %w", err)
+			}
+		}
+		if _, err := out.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("file write error: %w", err)
+		}
+		out.itemWritten()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := out.close(); err != nil {
+		return fmt.Errorf("file write error: %w", err)
+	}
+	return nil
+}
+
+// encodeXML encodes val as element with name. Maps are encoded element by element,
+// and slices as elements with value children.
+func encodeXML(encoder *xml.Encoder, name string, val reflect.Value) error {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return encoder.EncodeElement("", xml.StartElement{Name: xml.Name{Local: name}})
+		}
+		val = val.Elem()
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	switch {
+	case val.Kind() == reflect.Map:
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			keyName := xmlName(fmt.Sprint(key))
+			value := indirect(val.MapIndex(key))
+
+			// Slices in maps are repeated elements
+			if isXMLList(value) {
+				for i := 0; i < value.Len(); i++ {
+					if err := encodeXML(encoder, keyName, value.Index(i)); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := encodeXML(encoder, keyName, val.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		return encoder.EncodeToken(start.End())
+	case isXMLList(val):
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < val.Len(); i++ {
+			if err := encodeXML(encoder, "value", val.Index(i)); err != nil {
+				return err
+			}
+		}
+		return encoder.EncodeToken(start.End())
+	}
+	return encoder.EncodeElement(val.Interface(), start)
+}
+
+// isXMLList reports whether val is a slice or array, except byte slices which are encoded as text
+func isXMLList(val reflect.Value) bool {
+	return (val.Kind() == reflect.Slice || val.Kind() == reflect.Array) && val.Type().Elem().Kind() != reflect.Uint8
+}
+
+// xmlName converts s to a valid XML element name
+func xmlName(s string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, s)
+	first, _ := utf8.DecodeRuneInString(name)
+	if name == "" || !(unicode.IsLetter(first) || first == '_') || strings.HasPrefix(strings.ToLower(name), "xml") {
+		name = "_" + name
+	}
+	return name
+}
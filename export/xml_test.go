@@ -0,0 +1,105 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type xmlProduct struct {
+	ID    int      `xml:"id,attr"`
+	Name  string   `xml:"name"`
+	Tags  []string `xml:"tags>tag"`
+	Price float64  `xml:"-"`
+}
+
+func TestXMLExporter_Export(t *testing.T) {
+	exporter := &XML{
+		FileName:    "out.xml",
+		RootElement: "products",
+		ItemElement: "product",
+		Indent:      " ",
+	}
+	defer os.Remove(exporter.FileName)
+
+	export(t, exporter,
+		xmlProduct{ID: 1, Name: "Pen & <Paper>", Tags: []string{"a", "b"}, Price: 2},
+		map[string]interface{}{"name": "Book", "1st edition": true, "authors": []string{"x", "y"}, "size": map[string]int{"pages": 100}},
+		[]int{1, 2},
+	)
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<products>
+ <product id="1">
+  <name>Pen &amp; &lt;Paper&gt;</name>
+  <tags>
+   <tag>a</tag>
+   <tag>b</tag>
+  </tags>
+ </product>
+ <product>
+  <_1st_edition>true</_1st_edition>
+  <authors>x</authors>
+  <authors>y</authors>
+  <name>Book</name>
+  <size>
+   <pages>100</pages>
+  </size>
+ </product>
+ <product>
+  <value>1</value>
+  <value>2</value>
+ </product>
+</products>
+`, readFile(t, exporter.FileName))
+}
+
+func TestXMLExporter_FlushInterval(t *testing.T) {
+	exporter := &XML{
+		FileName:    "out.xml",
+		Indent:      " ",
+		FileOptions: FileOptions{FlushInterval: 10 * time.Millisecond, AtomicDisabled: true},
+	}
+	defer os.Remove(exporter.FileName)
+
+	// File is valid while exporting
+	exports, wait := startExport(t, exporter)
+	exports <- map[string]int{"a": 1}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, xml.Header+"<items>\n <item>\n  <a>1</a>\n </item>\n</items>\n", readFile(t, exporter.FileName))
+	exports <- map[string]int{"a": 2}
+	wait()
+
+	assert.Equal(t, xml.Header+"<items>\n <item>\n  <a>1</a>\n </item>\n <item>\n  <a>2</a>\n </item>\n</items>\n", readFile(t, exporter.FileName))
+}
+
+func TestXMLExporter_InvalidItem(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "xml")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	exporter := &XML{
+		FileName:    filepath.Join(tempDir, "out.xml"),
+		FileOptions: FileOptions{MaxItems: 1},
+	}
+	exports := make(chan interface{}, 3)
+	exports <- map[string]interface{}{"name": "pen"}
+	exports <- map[string]interface{}{"name": "book", "updates": make(chan int)}
+	exports <- map[string]interface{}{"name": "bag"}
+	close(exports)
+	assert.NoError(t, exporter.Export(exports))
+
+	// Invalid item is skipped without leaving its element open
+	for part, name := range []string{"pen", "bag"} {
+		contents, err := ioutil.ReadFile(filepath.Join(tempDir, fmt.Sprintf("out-%d.xml", part+1)))
+		assert.NoError(t, err)
+		assert.Equal(t, xml.Header+"<items><item><name>"+name+"</name></item></items>\n", string(contents))
+		assert.NoError(t, xml.Unmarshal(contents, new(interface{})))
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"github.com/geziyor/geziyor/client"
 	"github.com/geziyor/geziyor/export"
 	"github.com/geziyor/geziyor/internal"
+	"github.com/geziyor/geziyor/jar"
 	"github.com/geziyor/geziyor/metrics"
 	"github.com/geziyor/geziyor/middleware"
+	"golang.org/x/net/publicsuffix"
 	"golang.org/x/time/rate"
 
+	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http/cookiejar"
@@ -17,6 +21,9 @@ import (
 	"os/signal"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 // Geziyor is our main scraper type
@@ -26,8 +33,9 @@ type Geziyor struct {
 	Exports chan interface{}
 
 	metrics        *metrics.Metrics
-	reqMiddlewares []middleware.RequestProcessor
-	resMiddlewares []middleware.ResponseProcessor
+	reqMiddlewares []middleware.RequestProcessorV2
+	resMiddlewares []middleware.ResponseProcessorV2
+	errMiddlewares []middleware.ErrorProcessor
 	rateLimiter    *rate.Limiter
 	wgRequests     sync.WaitGroup
 	wgExporters    sync.WaitGroup
@@ -36,7 +44,7 @@ type Geziyor struct {
 		sync.RWMutex
 		hostSems map[string]chan struct{}
 	}
-	shutdown bool
+	shutdown int32
 }
 
 // NewGeziyor creates new Geziyor with default values.
@@ -56,32 +64,30 @@ func NewGeziyor(opt *Options) *Geziyor {
 	if len(opt.RetryHTTPCodes) == 0 {
 		opt.RetryHTTPCodes = client.DefaultRetryHTTPCodes
 	}
+	if opt.MaxRedirect == 0 {
+		opt.MaxRedirect = client.DefaultMaxRedirect
+	}
 
 	geziyor := &Geziyor{
 		Opt:     opt,
 		Exports: make(chan interface{}, 1),
-		reqMiddlewares: []middleware.RequestProcessor{
-			&middleware.AllowedDomains{AllowedDomains: opt.AllowedDomains},
-			&middleware.DuplicateRequests{RevisitEnabled: opt.URLRevisitEnabled},
-			&middleware.Headers{UserAgent: opt.UserAgent},
-			middleware.NewDelay(opt.RequestDelayRandomize, opt.RequestDelay),
-		},
-		resMiddlewares: []middleware.ResponseProcessor{
-			&middleware.ParseHTML{ParseHTMLDisabled: opt.ParseHTMLDisabled},
-			&middleware.LogStats{LogDisabled: opt.LogDisabled},
-		},
 		metrics: metrics.NewMetrics(opt.MetricsType),
 	}
 
 	// Client
+	if opt.ProxyPool != nil && opt.ProxyPool.Metrics == nil {
+		opt.ProxyPool.Metrics = geziyor.metrics
+	}
 	geziyor.Client = client.NewClient(&client.Options{
 		MaxBodySize:           opt.MaxBodySize,
+		MaxBodyAction:         opt.MaxBodyAction,
 		CharsetDetectDisabled: opt.CharsetDetectDisabled,
 		RetryTimes:            opt.RetryTimes,
 		RetryHTTPCodes:        opt.RetryHTTPCodes,
 		RemoteAllocatorURL:    opt.BrowserEndpoint,
 		AllocatorOptions:      chromedp.DefaultExecAllocatorOptions[:],
 		ProxyFunc:             opt.ProxyFunc,
+		ProxyPool:             opt.ProxyPool,
 		PreActions:            opt.PreActions,
 	})
 	if opt.Cache != nil {
@@ -90,17 +96,20 @@ func NewGeziyor(opt *Options) *Geziyor {
 			Transport:           geziyor.Client.Transport,
 			Cache:               opt.Cache,
 			MarkCachedResponses: true,
+			Fingerprinter:       opt.RequestFingerprinter,
 		}
 	}
 	if opt.Timeout != 0 {
 		geziyor.Client.Timeout = opt.Timeout
 	}
 	if !opt.CookiesDisabled {
-		geziyor.Client.Jar, _ = cookiejar.New(nil)
-	}
-	if opt.MaxRedirect != 0 {
-		geziyor.Client.CheckRedirect = client.NewRedirectionHandler(opt.MaxRedirect)
+		if opt.CookieJar != nil {
+			geziyor.Client.Jar = opt.CookieJar
+		} else {
+			geziyor.Client.Jar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		}
 	}
+	geziyor.Client.CheckRedirect = client.NewRedirectionHandler(opt.MaxRedirect)
 
 	// Concurrency
 	if opt.RequestsPerSecond != 0 {
@@ -116,17 +125,8 @@ func NewGeziyor(opt *Options) *Geziyor {
 		}{hostSems: make(map[string]chan struct{})}
 	}
 
-	// Base Middlewares
-	metricsMiddleware := &middleware.Metrics{Metrics: geziyor.metrics}
-	geziyor.reqMiddlewares = append(geziyor.reqMiddlewares, metricsMiddleware)
-	geziyor.resMiddlewares = append(geziyor.resMiddlewares, metricsMiddleware)
-
-	robotsMiddleware := middleware.NewRobotsTxt(geziyor.Client, geziyor.metrics, opt.RobotsTxtDisabled)
-	geziyor.reqMiddlewares = append(geziyor.reqMiddlewares, robotsMiddleware)
-
-	// Custom Middlewares
-	geziyor.reqMiddlewares = append(geziyor.reqMiddlewares, opt.RequestMiddlewares...)
-	geziyor.resMiddlewares = append(geziyor.resMiddlewares, opt.ResponseMiddlewares...)
+	// Middlewares
+	geziyor.reqMiddlewares, geziyor.resMiddlewares, geziyor.errMiddlewares = middleware.Chains(geziyor.middlewares())
 
 	// Logging
 	if opt.LogDisabled {
@@ -138,6 +138,101 @@ func NewGeziyor(opt *Options) *Geziyor {
 	return geziyor
 }
 
+// middlewares returns built-in and custom middlewares, with built-ins replaced or disabled according to options
+func (g *Geziyor) middlewares() []middleware.Middleware {
+	opt := g.Opt
+	metricsMiddleware := &middleware.Metrics{Metrics: g.metrics}
+	// Duplicate requests are checked after header middlewares, so that fingerprints can include their headers
+	builtins := []middleware.Middleware{
+		{Name: middleware.AllowedDomainsName, Order: 100, Processor: &middleware.AllowedDomains{
+			AllowedDomains: opt.AllowedDomains,
+			DeniedDomains:  opt.DeniedDomains,
+			Metrics:        g.metrics,
+		}},
+		{Name: middleware.DuplicateRequestsName, Order: 450, Processor: &middleware.DuplicateRequests{RevisitEnabled: opt.URLRevisitEnabled, Fingerprinter: opt.RequestFingerprinter, Filter: opt.DupeFilter}},
+		{Name: middleware.ParseHTMLName, Order: 100, Processor: &middleware.ParseHTML{ParseHTMLDisabled: opt.ParseHTMLDisabled}},
+		{Name: middleware.LogStatsName, Order: 200, Processor: &middleware.LogStats{LogDisabled: opt.LogDisabled}},
+		{Name: middleware.HeadersName, Order: 400, Processor: &middleware.Headers{UserAgent: opt.UserAgent}},
+		{Name: middleware.DelayName, Order: 500, Processor: middleware.NewDelay(opt.RequestDelayRandomize, opt.RequestDelay)},
+		{Name: middleware.MetricsName, Order: 600, Processor: metricsMiddleware},
+		{Name: middleware.RobotsTxtName, Order: 700, Processor: middleware.NewRobotsTxt(g.Client, g.metrics, opt.RobotsTxtDisabled)},
+	}
+	// Header profiles are set before default headers
+	if len(opt.HeaderProfiles) != 0 {
+		builtins = append(builtins, middleware.Middleware{Name: middleware.HeaderRotationName, Order: 300, Processor: &middleware.HeaderRotation{
+			Profiles:   opt.HeaderProfiles,
+			PerSession: opt.HeaderProfilesPerSession,
+		}})
+	}
+	if opt.RefererEnabled {
+		builtins = append(builtins, middleware.Middleware{Name: middleware.RefererName, Order: 800, Processor: &middleware.Referer{DefaultPolicy: opt.RefererPolicy}})
+	}
+	if opt.MetaRefreshEnabled {
+		builtins = append(builtins, middleware.Middleware{Name: middleware.MetaRefreshName, Order: 850, Processor: &middleware.MetaRefresh{
+			MaxDelay:    opt.MetaRefreshMaxDelay,
+			MaxRedirect: opt.MaxRedirect,
+		}})
+	}
+	if opt.HTTPErrorEnabled {
+		builtins = append(builtins, middleware.Middleware{Name: middleware.HTTPErrorName, Order: 900, Processor: &middleware.HTTPError{AllowedCodes: opt.HTTPErrorAllowedCodes}})
+	}
+
+	// Replace, disable or reorder built-ins
+	var middlewares []middleware.Middleware
+	for _, builtin := range builtins {
+		if internal.ContainsString(opt.DisabledMiddlewares, builtin.Name) {
+			continue
+		}
+		if order, exists := opt.MiddlewareOrders[builtin.Name]; exists {
+			builtin.Order = order
+		}
+		for _, custom := range opt.Middlewares {
+			if custom.Name == builtin.Name {
+				builtin.Processor = custom.Processor
+				if custom.Order != 0 {
+					builtin.Order = custom.Order
+				}
+			}
+		}
+		middlewares = append(middlewares, builtin)
+	}
+
+	// Custom Middlewares
+	for _, custom := range opt.Middlewares {
+		if !containsMiddleware(builtins, custom.Name) {
+			if custom.Order == 0 {
+				custom.Order = middleware.DefaultOrder
+			}
+			middlewares = append(middlewares, custom)
+		}
+	}
+	for _, reqMiddleware := range opt.RequestMiddlewares {
+		middlewares = append(middlewares, middleware.Middleware{Order: middleware.DefaultOrder, Processor: reqMiddleware})
+	}
+	for _, resMiddleware := range opt.ResponseMiddlewares {
+		middlewares = append(middlewares, middleware.Middleware{Order: middleware.DefaultOrder, Processor: resMiddleware})
+	}
+	for _, reqMiddleware := range opt.RequestMiddlewaresV2 {
+		middlewares = append(middlewares, middleware.Middleware{Order: middleware.DefaultOrder, Processor: reqMiddleware})
+	}
+	for _, resMiddleware := range opt.ResponseMiddlewaresV2 {
+		middlewares = append(middlewares, middleware.Middleware{Order: middleware.DefaultOrder, Processor: resMiddleware})
+	}
+	for _, errMiddleware := range opt.ErrorMiddlewares {
+		middlewares = append(middlewares, middleware.Middleware{Order: middleware.DefaultOrder, Processor: errMiddleware})
+	}
+	return middlewares
+}
+
+func containsMiddleware(middlewares []middleware.Middleware, name string) bool {
+	for _, m := range middlewares {
+		if name != "" && m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Start starts scraping
 func (g *Geziyor) Start() {
 	internal.Logger.Println("Scraping Started")
@@ -148,13 +243,20 @@ func (g *Geziyor) Start() {
 		defer metricsServer.Close()
 	}
 
+	// Seed cookies
+	if g.Opt.CookiesFile != "" {
+		if err := g.seedCookies(); err != nil {
+			internal.Logger.Printf("cookies seeding error: %v\n", err)
+		}
+	}
+
 	// Start Exporters
 	g.startExporters()
 
-	// Wait for SIGINT (interrupt) signal.
+	// Wait for SIGINT (interrupt) or SIGTERM signal.
 	shutdownChan := make(chan os.Signal, 1)
 	shutdownDoneChan := make(chan struct{})
-	signal.Notify(shutdownChan, os.Interrupt)
+	signal.Notify(shutdownChan, os.Interrupt, syscall.SIGTERM)
 	go g.interruptSignalWaiter(shutdownChan, shutdownDoneChan)
 
 	// Start Requests
@@ -169,6 +271,13 @@ func (g *Geziyor) Start() {
 	g.wgRequests.Wait()
 	close(g.Exports)
 	g.wgExporters.Wait()
+
+	// Write pending changes of persistent cookie jars
+	if closer, ok := g.Client.Jar.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			internal.Logger.Printf("Cookie jar closing error: %v\n", err)
+		}
+	}
 	shutdownDoneChan <- struct{}{}
 	internal.Logger.Println("Scraping Finished")
 }
@@ -183,6 +292,17 @@ func (g *Geziyor) Get(url string, callback func(g *Geziyor, r *client.Response))
 	g.Do(req, callback)
 }
 
+// Follow issues a GET to the URL relative to response, like a link of the page.
+// Request's Referer header is set from response if RefererEnabled.
+func (g *Geziyor) Follow(r *client.Response, url string, callback func(g *Geziyor, r *client.Response)) {
+	req, err := r.NewRequest("GET", url, nil)
+	if err != nil {
+		internal.Logger.Printf("Request creating error %v\n", err)
+		return
+	}
+	g.Do(req, callback)
+}
+
 // GetRendered issues GET request using headless browser
 // Opens up a new Chrome instance, makes request, waits for rendering HTML DOM and closed.
 // Rendered requests only supported for GET requests.
@@ -216,9 +336,60 @@ func (g *Geziyor) Post(url string, body io.Reader, callback func(g *Geziyor, r *
 	g.Do(req, callback)
 }
 
+// NewSession creates a new session with an isolated cookie jar and adds it to client.
+// Requests with Request.SessionID set to id will use this session.
+func (g *Geziyor) NewSession(id string) *client.Session {
+	session := client.NewSession(id)
+	if g.Opt.CookiesDisabled {
+		session.Jar = nil
+	}
+	g.Client.AddSession(session)
+	return session
+}
+
+// Session returns the session with provided ID
+func (g *Geziyor) Session(id string) (*client.Session, bool) {
+	return g.Client.Session(id)
+}
+
+// DiscardSession removes the session with provided ID.
+// Requests made afterwards with this session ID will fail.
+func (g *Geziyor) DiscardSession(id string) {
+	g.Client.DeleteSession(id)
+}
+
+// SaveSession persists the session with provided ID to file
+func (g *Geziyor) SaveSession(id string, fileName string) error {
+	session, ok := g.Client.Session(id)
+	if !ok {
+		return fmt.Errorf("%w: %s", client.ErrSessionNotFound, id)
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("session file creation error: %w", err)
+	}
+	defer file.Close()
+	return session.Save(file)
+}
+
+// LoadSession loads the session persisted by SaveSession and adds it to client
+func (g *Geziyor) LoadSession(fileName string) (*client.Session, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("session file open error: %w", err)
+	}
+	defer file.Close()
+	session, err := client.LoadSession(file)
+	if err != nil {
+		return nil, err
+	}
+	g.Client.AddSession(session)
+	return session, nil
+}
+
 // Do sends an HTTP request
 func (g *Geziyor) Do(req *client.Request, callback func(g *Geziyor, r *client.Response)) {
-	if g.shutdown {
+	if atomic.LoadInt32(&g.shutdown) != 0 {
 		return
 	}
 	g.wgRequests.Add(1)
@@ -232,29 +403,58 @@ func (g *Geziyor) Do(req *client.Request, callback func(g *Geziyor, r *client.Re
 // Do sends an HTTP request
 func (g *Geziyor) do(req *client.Request, callback func(g *Geziyor, r *client.Response)) {
 	g.acquireSem(req)
-	defer g.releaseSem(req)
+	semReleased := false
+	defer func() {
+		if !semReleased {
+			g.releaseSem(req)
+		}
+	}()
 	defer g.wgRequests.Done()
 	defer g.recoverMe()
 
+	// Session headers take precedence over default headers set by middlewares
+	g.Client.SetSessionHeaders(req)
+
+	ctx := req.Context()
+	var res *client.Response
+	var err error
 	for _, middlewareFunc := range g.reqMiddlewares {
-		middlewareFunc.ProcessRequest(req)
+		res, err = middlewareFunc.ProcessRequestV2(ctx, req)
 		if req.Cancelled {
+			if g.Opt.CancelledRequestsReported {
+				g.handleError(ctx, req, &client.CancelledError{Reason: req.CancelReason()})
+			}
 			return
 		}
+		if err != nil || res != nil {
+			break
+		}
+	}
+	if res != nil && res.Request == nil {
+		res.Request = req
 	}
 
-	res, err := g.Client.DoRequest(req)
+	if err == nil && res == nil {
+		res, err = g.Client.DoRequest(req)
+	}
 	if err != nil {
-		if g.Opt.ErrorFunc != nil {
-			g.Opt.ErrorFunc(g, req, err)
-		} else {
-			internal.Logger.Println(err)
-		}
+		g.handleError(ctx, req, err)
 		return
 	}
 
 	for _, middlewareFunc := range g.resMiddlewares {
-		middlewareFunc.ProcessResponse(res)
+		newReq, err := middlewareFunc.ProcessResponseV2(ctx, res)
+		if err != nil {
+			g.handleError(ctx, req, err)
+			return
+		}
+		if newReq != nil {
+			// Follow-up request acquires its own slot, so this one is released first
+			semReleased = true
+			g.releaseSem(req)
+			g.Do(newReq, callback)
+			return
+		}
 	}
 
 	// Callbacks
@@ -267,6 +467,20 @@ func (g *Geziyor) do(req *client.Request, callback func(g *Geziyor, r *client.Re
 	}
 }
 
+// handleError passes error to error middlewares, and to ErrorFunc if it's not handled by them.
+func (g *Geziyor) handleError(ctx context.Context, req *client.Request, err error) {
+	for _, middlewareFunc := range g.errMiddlewares {
+		if err = middlewareFunc.ProcessError(ctx, req, err); err == nil {
+			return
+		}
+	}
+	if g.Opt.ErrorFunc != nil {
+		g.Opt.ErrorFunc(g, req, err)
+	} else {
+		internal.Logger.Println(err)
+	}
+}
+
 func (g *Geziyor) acquireSem(req *client.Request) {
 	if g.rateLimiter != nil {
 		_ = g.rateLimiter.Wait(req.Context())
@@ -300,6 +514,19 @@ func (g *Geziyor) releaseSem(req *client.Request) {
 	}
 }
 
+// seedCookies sets cookies in CookiesFile to cookie jar
+func (g *Geziyor) seedCookies() error {
+	if g.Client.Jar == nil {
+		return client.ErrNoCookieJar
+	}
+	file, err := os.Open(g.Opt.CookiesFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return jar.ImportNetscape(g.Client.Jar, file)
+}
+
 // recoverMe prevents scraping being crashed.
 // Logs error and stack trace
 func (g *Geziyor) recoverMe() {
@@ -309,13 +536,14 @@ func (g *Geziyor) recoverMe() {
 	}
 }
 
-// interruptSignalWaiter waits data from provided channels and stops scraper if shutdownChan channel receives SIGINT
+// interruptSignalWaiter waits data from provided channels and stops scraper if shutdownChan channel receives SIGINT or SIGTERM.
+// Exporters are closed after ongoing requests are finished, so that exported files are completed.
 func (g *Geziyor) interruptSignalWaiter(shutdownChan chan os.Signal, shutdownDoneChan chan struct{}) {
 	for {
 		select {
-		case <-shutdownChan:
-			internal.Logger.Println("Received SIGINT, shutting down gracefully. Send again to force")
-			g.shutdown = true
+		case sig := <-shutdownChan:
+			internal.Logger.Printf("Received %v, shutting down gracefully. Send again to force\n", sig)
+			g.Stop()
 			signal.Stop(shutdownChan)
 		case <-shutdownDoneChan:
 			return
@@ -323,42 +551,109 @@ func (g *Geziyor) interruptSignalWaiter(shutdownChan chan os.Signal, shutdownDon
 	}
 }
 
+// Stop stops scraping gracefully, like SIGINT.
+// New requests are not made, and exporters are closed after ongoing requests are finished.
+func (g *Geziyor) Stop() {
+	atomic.StoreInt32(&g.shutdown, 1)
+}
+
 func (g *Geziyor) startExporters() {
-	if len(g.Opt.Exporters) != 0 {
-		var exporterChans []chan interface{}
-
-		g.wgExporters.Add(len(g.Opt.Exporters))
-		for _, exporter := range g.Opt.Exporters {
-			exporterChan := make(chan interface{})
-			exporterChans = append(exporterChans, exporterChan)
-			go func(exporter export.Exporter) {
-				defer g.wgExporters.Done()
-				if err := exporter.Export(exporterChan); err != nil {
-					internal.Logger.Printf("exporter error: %s\n", err)
-				}
-			}(exporter)
+	exporters := make([]export.ExporterV2, 0, len(g.Opt.Exporters)+len(g.Opt.ExportersV2))
+	for _, exporter := range g.Opt.Exporters {
+		exporters = append(exporters, export.AdaptExporter(exporter))
+	}
+	exporters = append(exporters, g.Opt.ExportersV2...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wgClosed sync.WaitGroup
+	var queues []*exporterQueue
+	for _, exporter := range exporters {
+		if err := exporter.Open(ctx); err != nil {
+			g.handleExportError(nil, fmt.Errorf("exporter open error: %w", err))
+			continue
+		}
+		bufferOptions := g.Opt.ExportBuffer
+		if buffered, ok := exporter.(*export.Buffered); ok {
+			bufferOptions = buffered.BufferOptions
 		}
-		go func() {
-			// When exports closed, close the exporter chans.
-			// Exports chan will be closed after all requests are handled.
-			defer func() {
-				for _, exporterChan := range exporterChans {
-					close(exporterChan)
+		queue := &exporterQueue{Queue: export.NewQueue(bufferOptions), name: export.ExporterName(exporter)}
+		queues = append(queues, queue)
+		g.wgExporters.Add(1)
+		wgClosed.Add(1)
+		go func(exporter export.ExporterV2) {
+			defer g.wgExporters.Done()
+			defer wgClosed.Done()
+			for {
+				item, queued, ok := queue.Pop()
+				if !ok {
+					break
 				}
-			}()
-			// Send incoming data from exports to all of the exporter's chans
-			for data := range g.Exports {
-				for _, exporterChan := range exporterChans {
-					exporterChan <- data
+				g.metrics.ExporterQueueGauge.With("exporter", queue.name).Set(float64(queue.Len()))
+				g.metrics.ExporterLagHistogram.With("exporter", queue.name).Observe(time.Since(queued).Seconds())
+				if err := exporter.ExportItem(ctx, item); err != nil {
+					g.handleExportError(item, err)
 				}
 			}
-		}()
-	} else {
-		g.wgExporters.Add(1)
-		go func() {
-			for range g.Exports {
+			if err := exporter.Close(); err != nil {
+				g.handleExportError(nil, fmt.Errorf("exporter close error: %w", err))
+			}
+		}(exporter)
+	}
+	go func() {
+		wgClosed.Wait()
+		cancel()
+	}()
+
+	g.wgExporters.Add(1)
+	go func() {
+		defer g.wgExporters.Done()
+		// When exports closed, close the exporter queues.
+		// Exports chan will be closed after all requests are handled.
+		defer func() {
+			for _, queue := range queues {
+				queue.Close()
 			}
-			g.wgExporters.Done()
 		}()
+		// Send incoming data from exports to all of the exporter's queues.
+		// Only exporters with full buffers of OverflowBlock policy block others.
+		for data := range g.Exports {
+			for _, queue := range queues {
+				g.pushExport(queue, data)
+			}
+		}
+	}()
+}
+
+// exporterQueue is the buffer of an exporter
+type exporterQueue struct {
+	*export.Queue
+	name          string
+	droppedLogged bool
+}
+
+// pushExport pushes item to exporter queue, and updates metrics of it
+func (g *Geziyor) pushExport(queue *exporterQueue, item interface{}) {
+	spilled, err := queue.Push(item)
+	switch {
+	case err == export.ErrQueueFull:
+		g.metrics.ExporterDroppedCounter.With("exporter", queue.name).Add(1)
+		if !queue.droppedLogged {
+			queue.droppedLogged = true
+			internal.Logger.Printf("Exporter %s buffer is full, dropping items\n", queue.name)
+		}
+	case err != nil:
+		g.handleExportError(item, fmt.Errorf("exporter buffer error: %w", err))
+	case spilled:
+		g.metrics.ExporterSpilledCounter.With("exporter", queue.name).Add(1)
+	}
+	g.metrics.ExporterQueueGauge.With("exporter", queue.name).Set(float64(queue.Len()))
+}
+
+// handleExportError calls ExportErrorFunc if it's defined, otherwise logs the error
+func (g *Geziyor) handleExportError(item interface{}, err error) {
+	if g.Opt.ExportErrorFunc != nil {
+		g.Opt.ExportErrorFunc(g, item, err)
+		return
 	}
+	internal.Logger.Printf("exporter error: %v\n", err)
 }
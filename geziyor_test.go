@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/chromedp/cdproto/dom"
 	"github.com/chromedp/chromedp"
@@ -24,6 +29,7 @@ import (
 	"github.com/geziyor/geziyor/export"
 	"github.com/geziyor/geziyor/internal"
 	"github.com/geziyor/geziyor/metrics"
+	"github.com/geziyor/geziyor/middleware"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -377,6 +383,447 @@ func TestProxy(t *testing.T) {
 	}).Start()
 }
 
+type shortCircuitMiddleware struct{}
+
+func (*shortCircuitMiddleware) ProcessRequestV2(_ context.Context, r *client.Request) (*client.Response, error) {
+	switch r.URL.Path {
+	case "/cached":
+		return &client.Response{Response: &http.Response{StatusCode: 200, Header: http.Header{}}, Body: []byte("cached")}, nil
+	case "/forbidden":
+		return nil, errors.New("forbidden")
+	}
+	return nil, nil
+}
+
+func (*shortCircuitMiddleware) ProcessResponseV2(_ context.Context, r *client.Response) (*client.Request, error) {
+	if r.Request.URL.Path == "/old" {
+		newURL, _ := r.Request.URL.Parse("/new")
+		return client.NewRequest("GET", newURL.String(), nil)
+	}
+	return nil, nil
+}
+
+func (*shortCircuitMiddleware) ProcessError(_ context.Context, r *client.Request, err error) error {
+	return fmt.Errorf("%s: %w", r.URL.Path, err)
+}
+
+func TestMiddlewaresV2(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	var bodies, errs []string
+	var mut sync.Mutex
+	m := &shortCircuitMiddleware{}
+	geziyor.NewGeziyor(&geziyor.Options{
+		StartRequestsFunc: func(g *geziyor.Geziyor) {
+			for _, path := range []string{"/cached", "/forbidden", "/old"} {
+				req, _ := client.NewRequest("GET", ts.URL+path, nil)
+				req.Synchronized = true
+				g.Do(req, nil)
+			}
+		},
+		ParseFunc: func(g *geziyor.Geziyor, r *client.Response) {
+			mut.Lock()
+			bodies = append(bodies, string(r.Body))
+			mut.Unlock()
+		},
+		ErrorFunc: func(g *geziyor.Geziyor, r *client.Request, err error) {
+			mut.Lock()
+			errs = append(errs, err.Error())
+			mut.Unlock()
+		},
+		RequestMiddlewaresV2:  []middleware.RequestProcessorV2{m},
+		ResponseMiddlewaresV2: []middleware.ResponseProcessorV2{m},
+		ErrorMiddlewares:      []middleware.ErrorProcessor{m},
+		RobotsTxtDisabled:     true,
+	}).Start()
+
+	assert.ElementsMatch(t, []string{"cached", "/new"}, bodies)
+	assert.Equal(t, []string{"/forbidden: forbidden"}, errs)
+}
+
+// syncRedirect redirects /old to /new with a synchronized request
+type syncRedirect struct{}
+
+func (*syncRedirect) ProcessResponseV2(_ context.Context, r *client.Response) (*client.Request, error) {
+	if r.Request.URL.Path != "/old" {
+		return nil, nil
+	}
+	req, err := r.NewRequest("GET", "/new", nil)
+	if err == nil {
+		req.Synchronized = true
+	}
+	return req, err
+}
+
+func TestFollowUpRequestReleasesSlot(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	var bodies []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		geziyor.NewGeziyor(&geziyor.Options{
+			StartURLs: []string{ts.URL + "/old"},
+			ParseFunc: func(g *geziyor.Geziyor, r *client.Response) {
+				bodies = append(bodies, string(r.Body))
+			},
+			ResponseMiddlewaresV2:       []middleware.ResponseProcessorV2{&syncRedirect{}},
+			ConcurrentRequests:          1,
+			ConcurrentRequestsPerDomain: 1,
+			RobotsTxtDisabled:           true,
+		}).Start()
+	}()
+
+	select {
+	case <-done:
+		assert.Equal(t, []string{"/new"}, bodies)
+	case <-time.After(5 * time.Second):
+		t.Fatal("follow-up request is blocked by concurrency limit")
+	}
+}
+
+// stripQuery removes query of requests, like a tracking parameter cleaner
+type stripQuery struct{}
+
+func (*stripQuery) ProcessRequest(r *client.Request) {
+	r.URL.RawQuery = ""
+}
+
+func TestMiddlewareOrdering(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /")
+			return
+		}
+		fmt.Fprint(w, r.URL.String())
+	}))
+	defer ts.Close()
+
+	var bodies []string
+	geziyor.NewGeziyor(&geziyor.Options{
+		StartRequestsFunc: func(g *geziyor.Geziyor) {
+			for _, query := range []string{"?utm=1", "?utm=2"} {
+				req, _ := client.NewRequest("GET", ts.URL+"/page"+query, nil)
+				req.Synchronized = true
+				g.Do(req, nil)
+			}
+		},
+		ParseFunc: func(g *geziyor.Geziyor, r *client.Response) {
+			bodies = append(bodies, string(r.Body))
+		},
+		// Strip query before duplicate requests check
+		Middlewares:         []middleware.Middleware{{Order: 150, Processor: &stripQuery{}}},
+		DisabledMiddlewares: []string{middleware.RobotsTxtName},
+	}).Start()
+
+	assert.Equal(t, []string{"/page"}, bodies)
+}
+
+func TestFingerprintIncludesDefaultHeaders(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer ts.Close()
+
+	fingerprinter := client.NewFingerprinter()
+	fingerprinter.Headers = []string{"Accept-Language"}
+	geziyor.NewGeziyor(&geziyor.Options{
+		StartRequestsFunc: func(g *geziyor.Geziyor) {
+			// Duplicate of the first one, after Headers middleware sets default Accept-Language
+			for _, language := range []string{"en", ""} {
+				req, _ := client.NewRequest("GET", ts.URL, nil)
+				if language != "" {
+					req.Header.Set("Accept-Language", language)
+				}
+				req.Synchronized = true
+				g.Do(req, nil)
+			}
+		},
+		RequestFingerprinter: fingerprinter,
+		DisabledMiddlewares:  []string{middleware.RobotsTxtName},
+	}).Start()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+type userAgentRecorder struct {
+	userAgent string
+}
+
+func (m *userAgentRecorder) ProcessRequest(r *client.Request) {
+	m.userAgent = r.Header.Get("User-Agent")
+}
+
+func TestMiddlewareDefaultOrder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// Custom middlewares without order run after built-ins, like Headers
+	recorder := &userAgentRecorder{}
+	geziyor.NewGeziyor(&geziyor.Options{
+		StartURLs:           []string{ts.URL},
+		UserAgent:           "custom",
+		Middlewares:         []middleware.Middleware{{Processor: recorder}},
+		DisabledMiddlewares: []string{middleware.RobotsTxtName},
+	}).Start()
+
+	assert.Equal(t, "custom", recorder.userAgent)
+}
+
+func TestHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(code)
+	}))
+	defer ts.Close()
+
+	var codes, errCodes []int
+	var mut sync.Mutex
+	geziyor.NewGeziyor(&geziyor.Options{
+		StartRequestsFunc: func(g *geziyor.Geziyor) {
+			for _, path := range []string{"/200", "/403", "/404", "/410"} {
+				req, _ := client.NewRequest("GET", ts.URL+path, nil)
+				if path == "/404" {
+					req.AllowedStatusCodes = []int{404}
+				}
+				g.Do(req, nil)
+			}
+		},
+		ParseFunc: func(g *geziyor.Geziyor, r *client.Response) {
+			mut.Lock()
+			codes = append(codes, r.StatusCode)
+			mut.Unlock()
+		},
+		ErrorFunc: func(g *geziyor.Geziyor, r *client.Request, err error) {
+			var statusErr *client.HTTPStatusError
+			if assert.True(t, errors.As(err, &statusErr)) {
+				mut.Lock()
+				errCodes = append(errCodes, statusErr.StatusCode)
+				mut.Unlock()
+			}
+		},
+		HTTPErrorEnabled:      true,
+		HTTPErrorAllowedCodes: []int{410},
+		RobotsTxtDisabled:     true,
+	}).Start()
+
+	assert.ElementsMatch(t, []int{200, 404, 410}, codes)
+	assert.Equal(t, []int{403}, errCodes)
+}
+
+func TestMetaRefresh(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/meta":
+			fmt.Fprint(w, `<html><head><meta http-equiv="refresh" content="0; url=/redirect"></head></html>`)
+		case "/redirect":
+			http.Redirect(w, r, "/target", http.StatusFound)
+		default:
+			fmt.Fprint(w, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	var bodies []string
+	var meta interface{}
+	var errs []error
+	run := func(maxRedirect int, parseHTMLDisabled bool) {
+		bodies, meta, errs = nil, nil, nil
+		geziyor.NewGeziyor(&geziyor.Options{
+			StartRequestsFunc: func(g *geziyor.Geziyor) {
+				req, _ := client.NewRequest("GET", ts.URL+"/meta", nil)
+				req.Meta["key"] = "value"
+				g.Do(req, func(g *geziyor.Geziyor, r *client.Response) {
+					bodies = append(bodies, string(r.Body))
+					meta = r.Request.Meta["key"]
+				})
+			},
+			ErrorFunc: func(g *geziyor.Geziyor, r *client.Request, err error) {
+				errs = append(errs, err)
+			},
+			MetaRefreshEnabled: true,
+			MaxRedirect:        maxRedirect,
+			RobotsTxtDisabled:  true,
+			ParseHTMLDisabled:  parseHTMLDisabled,
+		}).Start()
+	}
+
+	run(0, false)
+	assert.Equal(t, []string{"/target"}, bodies)
+	assert.Equal(t, "value", meta)
+	assert.Empty(t, errs)
+
+	// Meta refresh doesn't need parsed HTML
+	run(0, true)
+	assert.Equal(t, []string{"/target"}, bodies)
+	assert.Empty(t, errs)
+
+	// Meta refresh and HTTP redirect exceed max redirect of 1
+	run(1, false)
+	assert.Empty(t, bodies)
+	assert.Len(t, errs, 1)
+}
+
+func TestCancelledRequestsReported(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private")
+			return
+		}
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	var errs []error
+	var mut sync.Mutex
+	geziyor.NewGeziyor(&geziyor.Options{
+		StartRequestsFunc: func(g *geziyor.Geziyor) {
+			for _, path := range []string{"/public", "/public", "/private"} {
+				req, _ := client.NewRequest("GET", ts.URL+path, nil)
+				req.Synchronized = true
+				g.Do(req, nil)
+			}
+		},
+		ErrorFunc: func(g *geziyor.Geziyor, r *client.Request, err error) {
+			mut.Lock()
+			errs = append(errs, err)
+			mut.Unlock()
+		},
+		CancelledRequestsReported: true,
+	}).Start()
+
+	if assert.Len(t, errs, 2) {
+		var cancelledErr *client.CancelledError
+		var robotsErr *client.RobotsForbiddenError
+		assert.True(t, errors.As(errs[0], &cancelledErr))
+		assert.True(t, errors.Is(errs[0], client.ErrDuplicateRequest))
+		assert.True(t, errors.As(errs[1], &robotsErr))
+		assert.Equal(t, ts.URL+"/private", robotsErr.URL)
+	}
+}
+
+// recordingExporter records exported items and fails on "fail" items
+type recordingExporter struct {
+	sync.Mutex
+	opened bool
+	closed bool
+	items  []interface{}
+}
+
+func (e *recordingExporter) Open(ctx context.Context) error {
+	e.opened = true
+	return nil
+}
+
+func (e *recordingExporter) ExportItem(ctx context.Context, item interface{}) error {
+	if item == "fail" {
+		return errors.New("export failed")
+	}
+	e.Lock()
+	e.items = append(e.items, item)
+	e.Unlock()
+	return nil
+}
+
+func (e *recordingExporter) Close() error {
+	e.closed = true
+	return nil
+}
+
+func TestExportersV2(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	exporter := &recordingExporter{}
+	stopped := make(chan struct{})
+	var exportErrs []error
+	var requested []string
+	geziyor.NewGeziyor(&geziyor.Options{
+		StartRequestsFunc: func(g *geziyor.Geziyor) {
+			g.Exports <- "first"
+			g.Exports <- "fail"
+			g.Exports <- "second"
+			<-stopped
+			for _, path := range []string{"/a", "/b"} {
+				req, _ := client.NewRequest("GET", ts.URL+path, nil)
+				req.Synchronized = true
+				g.Do(req, func(g *geziyor.Geziyor, r *client.Response) {
+					requested = append(requested, string(r.Body))
+				})
+			}
+		},
+		ExportersV2: []export.ExporterV2{exporter},
+		ExportErrorFunc: func(g *geziyor.Geziyor, item interface{}, err error) {
+			exportErrs = append(exportErrs, err)
+			g.Stop()
+			close(stopped)
+		},
+	}).Start()
+
+	assert.True(t, exporter.opened)
+	assert.True(t, exporter.closed)
+	assert.Equal(t, []interface{}{"first", "second"}, exporter.items)
+	if assert.Len(t, exportErrs, 1) {
+		assert.EqualError(t, exportErrs[0], "export failed")
+	}
+	// Scraping is stopped after export error
+	assert.Empty(t, requested)
+}
+
+// slowExporter waits for release before exporting items
+type slowExporter struct {
+	recordingExporter
+	release chan struct{}
+}
+
+func (e *slowExporter) ExportItem(ctx context.Context, item interface{}) error {
+	<-e.release
+	return e.recordingExporter.ExportItem(ctx, item)
+}
+
+func TestExportBuffer(t *testing.T) {
+	fast := &recordingExporter{}
+	slow := &slowExporter{release: make(chan struct{})}
+	geziyor.NewGeziyor(&geziyor.Options{
+		StartRequestsFunc: func(g *geziyor.Geziyor) {
+			for i := 0; i < 5; i++ {
+				g.Exports <- i
+			}
+			// Fast exporter isn't blocked by slow one
+			for {
+				fast.Lock()
+				exported := len(fast.items)
+				fast.Unlock()
+				if exported == 5 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			close(slow.release)
+		},
+		ExportersV2: []export.ExporterV2{
+			fast,
+			&export.Buffered{ExporterV2: slow, BufferOptions: export.BufferOptions{Size: 1, Overflow: export.OverflowDrop}},
+		},
+	}).Start()
+
+	assert.Equal(t, []interface{}{0, 1, 2, 3, 4}, fast.items)
+	assert.True(t, slow.closed)
+	// Items are dropped while slow exporter's buffer is full
+	assert.NotEmpty(t, slow.items)
+	assert.Less(t, len(slow.items), 5)
+}
+
 // Make sure to increase open file descriptor limits before running
 func BenchmarkRequests(b *testing.B) {
 
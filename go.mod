@@ -9,6 +9,7 @@ require (
 	github.com/elazarl/goproxy v0.0.0-20210801061803-8e322dfb79c4
 	github.com/fortytw2/leaktest v1.3.0
 	github.com/go-kit/kit v0.12.0
+	github.com/golang/snappy v0.0.3
 	github.com/peterbourgon/diskv v2.0.1+incompatible
 	github.com/prometheus/client_golang v1.12.1
 	github.com/prometheus/common v0.34.0 // indirect
@@ -35,3 +35,11 @@ func ContainsInt(s []int, e int) bool {
 	}
 	return false
 }
+
+// DefaultInt returns first non-zero int
+func DefaultInt(val int, valDefault int) int {
+	if val != 0 {
+		return val
+	}
+	return valDefault
+}
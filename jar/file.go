@@ -0,0 +1,143 @@
+package jar
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/geziyor/geziyor/internal"
+)
+
+// FileStorage is an implementation of Storage that keeps cookies in a Netscape cookies.txt file.
+// Changes are written to file at most once per FlushInterval, and when storage is closed.
+// File is replaced atomically, so it's never half-written even if scraper is killed.
+type FileStorage struct {
+	// Interval of writing changes to file. Default: 1s
+	FlushInterval time.Duration
+
+	fileName string
+	mut      sync.Mutex
+	entries  map[string]*Entry
+	dirty    bool
+	timer    *time.Timer
+}
+
+// NewFileStorage returns a new FileStorage that will store cookies in fileName
+func NewFileStorage(fileName string) *FileStorage {
+	return &FileStorage{
+		fileName: fileName,
+		entries:  make(map[string]*Entry),
+	}
+}
+
+// Load returns cookie entries in file. If file doesn't exist, no entries returned.
+func (s *FileStorage) Load() ([]*Entry, error) {
+	file, err := os.Open(s.fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cookie file open: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := ReadNetscape(file)
+	if err != nil {
+		return nil, err
+	}
+	s.mut.Lock()
+	for _, entry := range entries {
+		s.entries[entry.Key()] = entry
+	}
+	s.mut.Unlock()
+	return entries, nil
+}
+
+// Set saves cookie entry to file as key
+func (s *FileStorage) Set(key string, entry *Entry) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.entries[key] = entry
+	s.changed()
+}
+
+// Delete removes cookie entry with key from file
+func (s *FileStorage) Delete(key string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if _, exists := s.entries[key]; !exists {
+		return
+	}
+	delete(s.entries, key)
+	s.changed()
+}
+
+// Flush writes changes to file
+func (s *FileStorage) Flush() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if !s.dirty {
+		return nil
+	}
+	if err := s.save(); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// Close writes changes to file. Storage can still be used after it's closed.
+func (s *FileStorage) Close() error {
+	return s.Flush()
+}
+
+// changed schedules writing changes to file
+func (s *FileStorage) changed() {
+	s.dirty = true
+	if s.timer == nil {
+		interval := s.FlushInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		s.timer = time.AfterFunc(interval, func() {
+			if err := s.Flush(); err != nil {
+				internal.Logger.Printf("cookie file write error: %v\n", err)
+			}
+		})
+	}
+}
+
+// save writes entries to a temporary file and renames it, so that file is never half-written.
+func (s *FileStorage) save() error {
+	entries := make([]*Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(s.fileName), filepath.Base(s.fileName)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("cookie file creation: %w", err)
+	}
+	if err := WriteNetscape(tempFile, entries); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("cookie file write: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("cookie file write: %w", err)
+	}
+	if err := os.Rename(tempFile.Name(), s.fileName); err != nil {
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("cookie file rename: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+// Package jar provides a persistent http.CookieJar implementation, so that
+// login state can be kept between runs.
+//
+// Cookie matching is done by net/http/cookiejar using the public suffix list.
+// Every accepted cookie is also recorded to a Storage, and replayed on creation.
+// Jar should be closed to write pending changes of storages that buffer them.
+package jar
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Entry is the persisted form of a cookie
+type Entry struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+	// HostOnly is true if cookie doesn't have a Domain attribute, so it's not sent to subdomains.
+	HostOnly bool `json:"host_only,omitempty"`
+}
+
+// Key returns the unique identifier of cookie entry
+func (e *Entry) Key() string {
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+// Expired checks whether cookie is expired. Session cookies never expire.
+func (e *Entry) Expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !e.Expires.After(now)
+}
+
+// URL returns a URL that cookie can be set for
+func (e *Entry) URL() *url.URL {
+	scheme := "http"
+	if e.Secure {
+		scheme = "https"
+	}
+	return &url.URL{Scheme: scheme, Host: e.Domain, Path: e.Path}
+}
+
+// Cookie converts entry to http.Cookie
+func (e *Entry) Cookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     e.Name,
+		Value:    e.Value,
+		Path:     e.Path,
+		Expires:  e.Expires,
+		Secure:   e.Secure,
+		HttpOnly: e.HttpOnly,
+	}
+	if !e.HostOnly {
+		cookie.Domain = e.Domain
+	}
+	return cookie
+}
+
+// Storage interface is used by Jar to persist cookie entries.
+type Storage interface {
+	// Load returns all persisted cookie entries
+	Load() ([]*Entry, error)
+	// Set stores cookie entry against key
+	Set(key string, entry *Entry)
+	// Delete removes cookie entry with key
+	Delete(key string)
+}
+
+// Jar is a persistent http.CookieJar.
+// Session cookies are persisted too, as they usually hold the login state.
+type Jar struct {
+	jar     *cookiejar.Jar
+	storage Storage
+	mut     sync.Mutex
+	entries map[string]*Entry
+}
+
+// New creates a new Jar that persists cookies to storage.
+// Non-expired cookies already in storage are loaded into jar.
+// If storage is nil, cookies are kept only in memory.
+func New(storage Storage) (*Jar, error) {
+	cookieJar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	j := &Jar{
+		jar:     cookieJar,
+		storage: storage,
+		entries: make(map[string]*Entry),
+	}
+	if storage != nil {
+		entries, err := storage.Load()
+		if err != nil {
+			return nil, err
+		}
+		// Loaded entries are already in storage
+		now := time.Now()
+		for _, entry := range entries {
+			if !entry.Expired(now) {
+				j.jar.SetCookies(entry.URL(), []*http.Cookie{entry.Cookie()})
+				j.entries[entry.Key()] = entry
+			}
+		}
+	}
+	return j, nil
+}
+
+// Close writes pending changes of storage, if storage has a Close method like FileStorage
+func (j *Jar) Close() error {
+	if closer, ok := j.storage.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Cookies implements the Cookies method of the http.CookieJar interface.
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// SetCookies implements the SetCookies method of the http.CookieJar interface.
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	for _, cookie := range cookies {
+		entry, ok := newEntry(u, cookie, now)
+		if !ok {
+			continue
+		}
+		if cookie.MaxAge < 0 || entry.Expired(now) {
+			j.deleteEntry(entry.Key())
+			continue
+		}
+		j.storeEntry(entry)
+	}
+}
+
+// Entries returns all non-expired cookie entries in jar
+func (j *Jar) Entries() []*Entry {
+	now := time.Now()
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	entries := make([]*Entry, 0, len(j.entries))
+	for _, entry := range j.entries {
+		if !entry.Expired(now) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// SetEntries sets cookie entries to jar, ignoring expired ones
+func (j *Jar) SetEntries(entries []*Entry) {
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Expired(now) {
+			continue
+		}
+		j.jar.SetCookies(entry.URL(), []*http.Cookie{entry.Cookie()})
+		j.mut.Lock()
+		j.storeEntry(entry)
+		j.mut.Unlock()
+	}
+}
+
+func (j *Jar) storeEntry(entry *Entry) {
+	j.entries[entry.Key()] = entry
+	if j.storage != nil {
+		j.storage.Set(entry.Key(), entry)
+	}
+}
+
+func (j *Jar) deleteEntry(key string) {
+	delete(j.entries, key)
+	if j.storage != nil {
+		j.storage.Delete(key)
+	}
+}
+
+// newEntry creates cookie entry for the cookie received from u.
+// Returns false if cookie would be rejected by the jar.
+func newEntry(u *url.URL, cookie *http.Cookie, now time.Time) (*Entry, bool) {
+	host := strings.ToLower(u.Hostname())
+	entry := &Entry{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Domain:   host,
+		Path:     cookie.Path,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		HostOnly: true,
+	}
+
+	if cookie.Domain != "" {
+		domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			return nil, false
+		}
+		// Cookies for public suffixes are only allowed as host cookies
+		if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain {
+			if host != domain {
+				return nil, false
+			}
+		} else {
+			entry.Domain = domain
+			entry.HostOnly = false
+		}
+	}
+
+	if entry.Path == "" || entry.Path[0] != '/' {
+		entry.Path = defaultPath(u.Path)
+	}
+
+	if cookie.MaxAge > 0 {
+		entry.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+	} else if !cookie.Expires.IsZero() {
+		entry.Expires = cookie.Expires
+	}
+	return entry, true
+}
+
+// defaultPath returns the directory part of an URL's path according to
+// RFC 6265 section 5.1.4.
+func defaultPath(path string) string {
+	if len(path) == 0 || path[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(path, "/")
+	if i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
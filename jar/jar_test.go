@@ -0,0 +1,121 @@
+package jar
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJarPersistsToFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "jar")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	fileName := filepath.Join(tempDir, "cookies.txt")
+
+	j, err := New(NewFileStorage(fileName))
+	assert.NoError(t, err)
+	u, _ := url.Parse("https://www.example.com/account/login")
+	j.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "1", HttpOnly: true},
+		{Name: "pref", Value: "dark", Domain: "example.com", Path: "/", MaxAge: 3600},
+		{Name: "removed", Value: "x", MaxAge: -1},
+	})
+	assert.NoError(t, j.Close())
+
+	j, err = New(NewFileStorage(fileName))
+	assert.NoError(t, err)
+	assert.Len(t, j.Entries(), 2)
+
+	sub, _ := url.Parse("https://shop.example.com/")
+	cookies := j.Cookies(sub)
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "pref", cookies[0].Name)
+
+	cookies = j.Cookies(u)
+	assert.Len(t, cookies, 2)
+}
+
+// countingStorage counts writes to storage
+type countingStorage struct {
+	entries []*Entry
+	writes  int
+}
+
+func (s *countingStorage) Load() ([]*Entry, error) { return s.entries, nil }
+func (s *countingStorage) Set(string, *Entry)      { s.writes++ }
+func (s *countingStorage) Delete(string)           { s.writes++ }
+
+func TestJarLoadDoesNotWriteStorage(t *testing.T) {
+	storage := &countingStorage{entries: []*Entry{
+		{Name: "a", Value: "1", Domain: "example.com", Path: "/", HostOnly: true},
+		{Name: "b", Value: "2", Domain: "example.com", Path: "/", HostOnly: true},
+	}}
+	j, err := New(storage)
+	assert.NoError(t, err)
+	assert.Len(t, j.Entries(), 2)
+	assert.Equal(t, 0, storage.writes)
+}
+
+func TestFileStorageBatchesWrites(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "jar")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	fileName := filepath.Join(tempDir, "cookies.txt")
+
+	storage := NewFileStorage(fileName)
+	storage.FlushInterval = time.Hour
+	j, err := New(storage)
+	assert.NoError(t, err)
+	u, _ := url.Parse("https://example.com/")
+	for i := 0; i < 100; i++ {
+		j.SetCookies(u, []*http.Cookie{{Name: "c" + strconv.Itoa(i), Value: "v"}})
+	}
+
+	// Nothing is written until flush interval or close
+	_, err = os.Stat(fileName)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, j.Close())
+	entries, err := NewFileStorage(fileName).Load()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 100)
+}
+
+func TestJarRejectsPublicSuffixDomain(t *testing.T) {
+	j, err := New(nil)
+	assert.NoError(t, err)
+	u, _ := url.Parse("https://example.co.uk/")
+	j.SetCookies(u, []*http.Cookie{{Name: "evil", Value: "1", Domain: "co.uk"}})
+	assert.Len(t, j.Entries(), 0)
+}
+
+func TestNetscapeImportExport(t *testing.T) {
+	expires := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	input := "# Netscape HTTP Cookie File\n" +
+		".example.com\tTRUE\t/\tTRUE\t" + expires + "\ttoken\tabc\n" +
+		"#HttpOnly_www.example.com\tFALSE\t/app\tFALSE\t0\tsid\txyz\n" +
+		".example.com\tTRUE\t/\tFALSE\t1\texpired\tvalue\n"
+
+	j, err := New(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, j.Import(strings.NewReader(input)))
+	assert.Len(t, j.Entries(), 2)
+
+	var buf bytes.Buffer
+	assert.NoError(t, j.Export(&buf))
+	assert.Equal(t, "# Netscape HTTP Cookie File\n"+
+		".example.com\tTRUE\t/\tTRUE\t"+expires+"\ttoken\tabc\n"+
+		"#HttpOnly_www.example.com\tFALSE\t/app\tFALSE\t0\tsid\txyz\n", buf.String())
+
+	u, _ := url.Parse("https://www.example.com/app/page")
+	assert.Len(t, j.Cookies(u), 2)
+}
@@ -0,0 +1,63 @@
+// Package leveldbjar provides an implementation of jar.Storage that
+// uses github.com/syndtr/goleveldb/leveldb
+package leveldbjar
+
+import (
+	"encoding/json"
+
+	"github.com/geziyor/geziyor/jar"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Storage is an implementation of jar.Storage with leveldb storage
+type Storage struct {
+	Db *leveldb.DB
+}
+
+// Load returns all cookie entries in leveldb
+func (s *Storage) Load() ([]*jar.Entry, error) {
+	var entries []*jar.Entry
+	iter := s.Db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		var entry jar.Entry
+		if err := json.Unmarshal(iter.Value(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, iter.Error()
+}
+
+// Set saves a cookie entry to leveldb as key
+func (s *Storage) Set(key string, entry *jar.Entry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	_ = s.Db.Put([]byte(key), data, nil)
+}
+
+// Delete removes the cookie entry with key from leveldb
+func (s *Storage) Delete(key string) {
+	_ = s.Db.Delete([]byte(key), nil)
+}
+
+// New returns a new Storage that will store leveldb in path
+func New(path string) (*Storage, error) {
+	storage := &Storage{}
+
+	var err error
+	storage.Db, err = leveldb.OpenFile(path, nil)
+
+	if err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+// NewWithDB returns a new Storage using the provided leveldb as underlying
+// storage.
+func NewWithDB(db *leveldb.DB) *Storage {
+	return &Storage{db}
+}
@@ -0,0 +1,39 @@
+package leveldbjar
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/geziyor/geziyor/jar"
+)
+
+func TestLevelDBJar(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "jar")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	storage, err := New(filepath.Join(tempDir, "Db"))
+	if err != nil {
+		t.Fatalf("New leveldb: %v", err)
+	}
+	j, err := jar.New(storage)
+	if err != nil {
+		t.Fatalf("New jar: %v", err)
+	}
+	u, _ := url.Parse("https://example.com/")
+	j.SetCookies(u, []*http.Cookie{{Name: "session", Value: "1"}})
+
+	j, err = jar.New(storage)
+	if err != nil {
+		t.Fatalf("New jar: %v", err)
+	}
+	if cookies := j.Cookies(u); len(cookies) != 1 || cookies[0].Value != "1" {
+		t.Fatalf("cookie not persisted: %v", cookies)
+	}
+}
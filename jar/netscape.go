@@ -0,0 +1,125 @@
+package jar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	netscapeHeader   = "# Netscape HTTP Cookie File\n"
+	httpOnlyPrefix   = "#HttpOnly_"
+	netscapeFieldLen = 7
+)
+
+// ReadNetscape parses cookie entries in Netscape cookies.txt format,
+// as exported by browsers and curl.
+func ReadNetscape(r io.Reader) ([]*Entry, error) {
+	var entries []*Entry
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != netscapeFieldLen {
+			return nil, fmt.Errorf("cookies.txt line %d: expected %d fields, got %d", lineNumber, netscapeFieldLen, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cookies.txt line %d: expiry parse: %w", lineNumber, err)
+		}
+
+		entry := &Entry{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+		if expires != 0 {
+			entry.Expires = time.Unix(expires, 0)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cookies.txt read: %w", err)
+	}
+	return entries, nil
+}
+
+// WriteNetscape writes cookie entries in Netscape cookies.txt format.
+// Session cookies are written with zero expiry.
+func WriteNetscape(w io.Writer, entries []*Entry) error {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key() < entries[j].Key()
+	})
+
+	writer := bufio.NewWriter(w)
+	if _, err := writer.WriteString(netscapeHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		domain, includeSubdomains := entry.Domain, "FALSE"
+		if !entry.HostOnly {
+			domain, includeSubdomains = "."+entry.Domain, "TRUE"
+		}
+		if entry.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if !entry.Expires.IsZero() {
+			expires = entry.Expires.Unix()
+		}
+		_, err := fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, includeSubdomains, entry.Path, strings.ToUpper(strconv.FormatBool(entry.Secure)), expires, entry.Name, entry.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// ImportNetscape reads cookies in Netscape cookies.txt format and sets them to any http.CookieJar.
+// Expired cookies are ignored.
+func ImportNetscape(cookieJar http.CookieJar, r io.Reader) error {
+	entries, err := ReadNetscape(r)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if !entry.Expired(now) {
+			cookieJar.SetCookies(entry.URL(), []*http.Cookie{entry.Cookie()})
+		}
+	}
+	return nil
+}
+
+// Import reads cookies in Netscape cookies.txt format and sets them to jar.
+func (j *Jar) Import(r io.Reader) error {
+	entries, err := ReadNetscape(r)
+	if err != nil {
+		return err
+	}
+	j.SetEntries(entries)
+	return nil
+}
+
+// Export writes all non-expired cookies in jar in Netscape cookies.txt format.
+func (j *Jar) Export(w io.Writer) error {
+	return WriteNetscape(w, j.Entries())
+}
@@ -32,6 +32,15 @@ type Metrics struct {
 	RobotsTxtRequestCounter   metrics.Counter
 	RobotsTxtResponseCounter  metrics.Counter
 	RobotsTxtForbiddenCounter metrics.Counter
+	ProxyRequestCounter       metrics.Counter
+	ProxyBenchedCounter       metrics.Counter
+	ProxyLatencyHistogram     metrics.Histogram
+	OffsiteFilteredCounter    metrics.Counter
+	TruncatedResponseCounter  metrics.Counter
+	ExporterQueueGauge        metrics.Gauge
+	ExporterLagHistogram      metrics.Histogram
+	ExporterDroppedCounter    metrics.Counter
+	ExporterSpilledCounter    metrics.Counter
 }
 
 // NewMetrics creates new metrics with given metrics.Type
@@ -45,6 +54,15 @@ func NewMetrics(metricsType Type) *Metrics {
 			RobotsTxtRequestCounter:   discard.NewCounter(),
 			RobotsTxtResponseCounter:  discard.NewCounter(),
 			RobotsTxtForbiddenCounter: discard.NewCounter(),
+			ProxyRequestCounter:       discard.NewCounter(),
+			ProxyBenchedCounter:       discard.NewCounter(),
+			ProxyLatencyHistogram:     discard.NewHistogram(),
+			OffsiteFilteredCounter:    discard.NewCounter(),
+			TruncatedResponseCounter:  discard.NewCounter(),
+			ExporterQueueGauge:        discard.NewGauge(),
+			ExporterLagHistogram:      discard.NewHistogram(),
+			ExporterDroppedCounter:    discard.NewCounter(),
+			ExporterSpilledCounter:    discard.NewCounter(),
 		}
 	case ExpVar:
 		return &Metrics{
@@ -54,6 +72,15 @@ func NewMetrics(metricsType Type) *Metrics {
 			RobotsTxtRequestCounter:   expvar.NewCounter("robotstxt_request_count"),
 			RobotsTxtResponseCounter:  expvar.NewCounter("robotstxt_response_count"),
 			RobotsTxtForbiddenCounter: expvar.NewCounter("robotstxt_forbidden_count"),
+			ProxyRequestCounter:       expvar.NewCounter("proxy_request_count"),
+			ProxyBenchedCounter:       expvar.NewCounter("proxy_benched_count"),
+			ProxyLatencyHistogram:     expvar.NewHistogram("proxy_latency_seconds", 50),
+			OffsiteFilteredCounter:    expvar.NewCounter("offsite_filtered_count"),
+			TruncatedResponseCounter:  expvar.NewCounter("truncated_response_count"),
+			ExporterQueueGauge:        expvar.NewGauge("exporter_queue_depth"),
+			ExporterLagHistogram:      expvar.NewHistogram("exporter_lag_seconds", 50),
+			ExporterDroppedCounter:    expvar.NewCounter("exporter_dropped_count"),
+			ExporterSpilledCounter:    expvar.NewCounter("exporter_spilled_count"),
 		}
 	case Prometheus:
 		return &Metrics{
@@ -87,6 +114,51 @@ func NewMetrics(metricsType Type) *Metrics {
 				Name:      "robotstxt_forbidden_count",
 				Help:      "Robotstxt forbidden count",
 			}, []string{"method"}),
+			ProxyRequestCounter: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "geziyor",
+				Name:      "proxy_request_count",
+				Help:      "Proxy request count",
+			}, []string{"proxy", "result"}),
+			ProxyBenchedCounter: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "geziyor",
+				Name:      "proxy_benched_count",
+				Help:      "Proxy benched count",
+			}, []string{"proxy", "reason"}),
+			ProxyLatencyHistogram: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+				Namespace: "geziyor",
+				Name:      "proxy_latency_seconds",
+				Help:      "Proxy latency in seconds",
+			}, []string{"proxy"}),
+			OffsiteFilteredCounter: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "geziyor",
+				Name:      "offsite_filtered_count",
+				Help:      "Offsite filtered request count",
+			}, []string{"domain"}),
+			TruncatedResponseCounter: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "geziyor",
+				Name:      "truncated_response_count",
+				Help:      "Response count exceeding max body size",
+			}, []string{"action"}),
+			ExporterQueueGauge: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+				Namespace: "geziyor",
+				Name:      "exporter_queue_depth",
+				Help:      "Items waiting in exporter buffer",
+			}, []string{"exporter"}),
+			ExporterLagHistogram: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+				Namespace: "geziyor",
+				Name:      "exporter_lag_seconds",
+				Help:      "Time between item is exported and exporter receives it, in seconds",
+			}, []string{"exporter"}),
+			ExporterDroppedCounter: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "geziyor",
+				Name:      "exporter_dropped_count",
+				Help:      "Items dropped because exporter buffer is full",
+			}, []string{"exporter"}),
+			ExporterSpilledCounter: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "geziyor",
+				Name:      "exporter_spilled_count",
+				Help:      "Items spilled to disk because exporter buffer is full",
+			}, []string{"exporter"}),
 		}
 	default:
 		return nil
@@ -1,23 +1,108 @@
 package middleware
 
 import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+
 	"github.com/geziyor/geziyor/client"
 	"github.com/geziyor/geziyor/internal"
-	"sync"
+	"github.com/geziyor/geziyor/metrics"
 )
 
-// AllowedDomains checks for request host if it exists in AllowedDomains
+// RegexpDomainPrefix is the prefix of domain patterns that are regular expressions
+const RegexpDomainPrefix = "regexp:"
+
+// AllowedDomains checks for request host if it exists in AllowedDomains and not in DeniedDomains.
+// Ports are ignored. Domain patterns can be:
+//   - Domain: "example.com" matches example.com and all of its subdomains
+//   - Wildcard: "*.example.com" matches only subdomains, "shop-*.example.com" matches shop-1.example.com etc.
+//   - Regular expression: "regexp:^api[0-9]+\.example\.com$"
 type AllowedDomains struct {
 	AllowedDomains []string
+	DeniedDomains  []string
+	Metrics        *metrics.Metrics
 	logOnlyOnce    sync.Map
+
+	compileOnce     sync.Once
+	allowedMatchers []domainMatcher
+	deniedMatchers  []domainMatcher
 }
 
 func (a *AllowedDomains) ProcessRequest(r *client.Request) {
-	if len(a.AllowedDomains) != 0 && !internal.ContainsString(a.AllowedDomains, r.Host) {
-		if _, logged := a.logOnlyOnce.LoadOrStore(r.Host, struct{}{}); !logged {
-			internal.Logger.Printf("Domain not allowed: %s\n", r.Host)
+	a.compileOnce.Do(func() {
+		a.allowedMatchers = compileDomainMatchers(a.AllowedDomains)
+		a.deniedMatchers = compileDomainMatchers(a.DeniedDomains)
+	})
+
+	host := strings.ToLower(r.URL.Hostname())
+	denied := matchDomain(a.deniedMatchers, host)
+	if denied || (len(a.AllowedDomains) != 0 && !matchDomain(a.allowedMatchers, host)) {
+		if _, logged := a.logOnlyOnce.LoadOrStore(host, struct{}{}); !logged {
+			internal.Logger.Printf("Domain not allowed: %s\n", host)
+		}
+		if a.Metrics != nil {
+			a.Metrics.OffsiteFilteredCounter.With("domain", host).Add(1)
 		}
-		r.Cancel()
+		r.CancelWithReason(fmt.Errorf("%w: %s", client.ErrDomainNotAllowed, host))
 		return
 	}
 }
+
+// domainMatcher matches a host to a domain pattern
+type domainMatcher func(host string) bool
+
+func compileDomainMatchers(patterns []string) []domainMatcher {
+	var matchers []domainMatcher
+	for _, pattern := range patterns {
+		matcher, err := compileDomainMatcher(pattern)
+		if err != nil {
+			internal.Logger.Printf("Domain pattern compile error %s: %v\n", pattern, err)
+			continue
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers
+}
+
+func compileDomainMatcher(pattern string) (domainMatcher, error) {
+	if strings.HasPrefix(pattern, RegexpDomainPrefix) {
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, RegexpDomainPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+
+	domain := strings.ToLower(pattern)
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+
+	if strings.Contains(domain, "*") {
+		parts := strings.Split(domain, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		re, err := regexp.Compile("^" + strings.Join(parts, "[^.]+(\\.[^.]+)*") + "$")
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+
+	return func(host string) bool {
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}, nil
+}
+
+func matchDomain(matchers []domainMatcher, host string) bool {
+	for _, matcher := range matchers {
+		if matcher(host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/geziyor/geziyor/client"
+	"github.com/geziyor/geziyor/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowedDomains_ProcessRequest(t *testing.T) {
+	allowedDomains := AllowedDomains{
+		AllowedDomains: []string{"example.com", "shop-*.store.com", `regexp:^api[0-9]+\.service\.com$`},
+		DeniedDomains:  []string{"private.example.com"},
+		Metrics:        metrics.NewMetrics(metrics.Discard),
+	}
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/", true},
+		{"https://www.example.com/", true},
+		{"https://example.com:8080/", true},
+		{"https://notexample.com/", false},
+		{"https://private.example.com/", false},
+		{"https://a.private.example.com/", false},
+		{"https://shop-1.store.com/", true},
+		{"https://store.com/", false},
+		{"https://api12.service.com/", true},
+		{"https://api.service.com/", false},
+	}
+	for _, tt := range tests {
+		req, err := client.NewRequest("GET", tt.url, nil)
+		assert.NoError(t, err)
+		allowedDomains.ProcessRequest(req)
+		assert.Equal(t, tt.allowed, !req.Cancelled, tt.url)
+	}
+}
@@ -2,25 +2,58 @@ package middleware
 
 import (
 	"github.com/geziyor/geziyor/client"
+	"github.com/geziyor/geziyor/dupefilter"
 	"github.com/geziyor/geziyor/internal"
 	"sync"
 )
 
-// DuplicateRequests checks for already visited URLs
+// DuplicateRequests checks for already visited requests.
+// Requests are compared by their fingerprints, so that URLs only differing
+// in query order, tracking parameters or fragments, and POSTs with identical bodies are duplicates.
 type DuplicateRequests struct {
 	RevisitEnabled bool
-	visitedURLs    sync.Map
-	logOnlyOnce    sync.Map
+	// Fingerprinter of requests. Default: client.DefaultFingerprinter
+	Fingerprinter client.RequestFingerprinter
+	// Filter of visited request fingerprints. Default: dupefilter.NewMemory()
+	Filter dupefilter.Filter
+	// LogAll logs every duplicate request. By default, only the first one is logged.
+	LogAll bool
+
+	filterOnce sync.Once
+	logOnce    sync.Once
 }
 
 func (a *DuplicateRequests) ProcessRequest(r *client.Request) {
-	if !a.RevisitEnabled && r.Request.Method == "GET" {
-		requestURL := r.Request.URL.String()
-		if _, visited := a.visitedURLs.LoadOrStore(requestURL, struct{}{}); visited {
-			if _, logged := a.logOnlyOnce.LoadOrStore(requestURL, struct{}{}); !logged {
-				internal.Logger.Printf("URL already visited %s\n", requestURL)
-			}
-			r.Cancel()
+	if a.RevisitEnabled {
+		return
+	}
+	a.filterOnce.Do(func() {
+		if a.Filter == nil {
+			a.Filter = dupefilter.NewMemory()
+		}
+	})
+	fingerprinter := a.Fingerprinter
+	if fingerprinter == nil {
+		fingerprinter = client.DefaultFingerprinter
+	}
+	fingerprint, err := fingerprinter.Fingerprint(r.Request)
+	if err != nil {
+		internal.Logger.Printf("Request fingerprint error %s: %v\n", r.URL, err)
+		return
+	}
+	visited, err := a.Filter.Visit(fingerprint)
+	if err != nil {
+		internal.Logger.Printf("Duplicate filter error %s: %v\n", r.URL, err)
+		return
+	}
+	if visited {
+		if a.LogAll {
+			internal.Logger.Printf("URL already visited %s %s\n", r.Method, r.URL)
+		} else {
+			a.logOnce.Do(func() {
+				internal.Logger.Printf("URL already visited %s %s (no more duplicates will be logged, see LogAll)\n", r.Method, r.URL)
+			})
 		}
+		r.CancelWithReason(client.ErrDuplicateRequest)
 	}
 }
@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"bytes"
 	"github.com/geziyor/geziyor/client"
+	"github.com/geziyor/geziyor/dupefilter"
+	"github.com/geziyor/geziyor/internal"
 	"github.com/stretchr/testify/assert"
 	"strings"
 	"testing"
@@ -20,3 +23,54 @@ func TestDuplicateRequests_ProcessRequest(t *testing.T) {
 	duplicateRequestsProcessor.ProcessRequest(req2)
 	duplicateRequestsProcessor.ProcessRequest(req2)
 }
+
+func TestDuplicateRequests_Fingerprint(t *testing.T) {
+	tests := []struct {
+		method    string
+		url       string
+		body      string
+		duplicate bool
+	}{
+		{"GET", "https://example.com/?b=2&a=1", "", false},
+		{"GET", "https://EXAMPLE.com/?a=1&b=2#top", "", true},
+		{"GET", "https://example.com/?a=1&b=2&utm_source=news", "", true},
+		{"GET", "https://example.com/?a=1&b=3", "", false},
+		{"POST", "https://example.com/search", "q=geziyor", false},
+		{"POST", "https://example.com/search", "q=geziyor", true},
+		{"POST", "https://example.com/search", "q=scraper", false},
+	}
+
+	duplicateRequestsProcessor := DuplicateRequests{}
+	for _, tt := range tests {
+		req, err := client.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+		assert.NoError(t, err)
+		duplicateRequestsProcessor.ProcessRequest(req)
+		assert.Equal(t, tt.duplicate, req.Cancelled, tt.method+" "+tt.url)
+	}
+}
+
+func TestDuplicateRequests_Bloom(t *testing.T) {
+	duplicateRequestsProcessor := DuplicateRequests{Filter: dupefilter.NewBloom(10, 0.001)}
+	for i := 0; i < 2; i++ {
+		req, err := client.NewRequest("GET", "https://example.com/?page=1", nil)
+		assert.NoError(t, err)
+		duplicateRequestsProcessor.ProcessRequest(req)
+		assert.Equal(t, i == 1, req.Cancelled)
+	}
+}
+
+func TestDuplicateRequests_LogOncePerInstance(t *testing.T) {
+	var buf bytes.Buffer
+	defer internal.Logger.SetOutput(internal.Logger.Writer())
+	internal.Logger.SetOutput(&buf)
+
+	for i := 0; i < 2; i++ {
+		duplicateRequestsProcessor := DuplicateRequests{}
+		for j := 0; j < 3; j++ {
+			req, err := client.NewRequest("GET", "https://example.com/", nil)
+			assert.NoError(t, err)
+			duplicateRequestsProcessor.ProcessRequest(req)
+		}
+	}
+	assert.Equal(t, 2, strings.Count(buf.String(), "URL already visited"))
+}
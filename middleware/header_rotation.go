@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/geziyor/geziyor/client"
+	"golang.org/x/net/publicsuffix"
+)
+
+// HeaderField is a single header of HeaderProfile
+type HeaderField struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// HeaderProfile is a coherent set of headers that a real browser sends.
+// Only header values are applied, order of headers isn't: net/http writes HTTP/1 headers sorted by key
+// and HTTP/2 headers in map order, so a realistic order needs a custom transport, which is out of scope.
+// Value of Sec-Fetch-Site is derived from the page that request is created from (Request.Referrer), or its Referer header.
+type HeaderProfile struct {
+	Name    string        `json:"name"`
+	Headers []HeaderField `json:"headers"`
+}
+
+// DefaultHeaderProfiles are built-in browser header profiles.
+// Accept-Encoding is left out intentionally, so that net/http can decompress responses transparently.
+var DefaultHeaderProfiles = []*HeaderProfile{
+	{
+		Name: "chrome-windows",
+		Headers: []HeaderField{
+			{"sec-ch-ua", `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`},
+			{"sec-ch-ua-mobile", "?0"},
+			{"sec-ch-ua-platform", `"Windows"`},
+			{"Upgrade-Insecure-Requests", "1"},
+			{"User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+			{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+			{"Sec-Fetch-Site", "none"},
+			{"Sec-Fetch-Mode", "navigate"},
+			{"Sec-Fetch-User", "?1"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Accept-Language", "en-US,en;q=0.9"},
+		},
+	},
+	{
+		Name: "chrome-macos",
+		Headers: []HeaderField{
+			{"sec-ch-ua", `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`},
+			{"sec-ch-ua-mobile", "?0"},
+			{"sec-ch-ua-platform", `"macOS"`},
+			{"Upgrade-Insecure-Requests", "1"},
+			{"User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+			{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+			{"Sec-Fetch-Site", "none"},
+			{"Sec-Fetch-Mode", "navigate"},
+			{"Sec-Fetch-User", "?1"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Accept-Language", "en-US,en;q=0.9"},
+		},
+	},
+	{
+		Name: "edge-windows",
+		Headers: []HeaderField{
+			{"sec-ch-ua", `"Not_A Brand";v="8", "Chromium";v="120", "Microsoft Edge";v="120"`},
+			{"sec-ch-ua-mobile", "?0"},
+			{"sec-ch-ua-platform", `"Windows"`},
+			{"Upgrade-Insecure-Requests", "1"},
+			{"User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0"},
+			{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+			{"Sec-Fetch-Site", "none"},
+			{"Sec-Fetch-Mode", "navigate"},
+			{"Sec-Fetch-User", "?1"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Accept-Language", "en-US,en;q=0.9"},
+		},
+	},
+	{
+		Name: "firefox-windows",
+		Headers: []HeaderField{
+			{"User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"},
+			{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"},
+			{"Accept-Language", "en-US,en;q=0.5"},
+			{"Upgrade-Insecure-Requests", "1"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Sec-Fetch-Mode", "navigate"},
+			{"Sec-Fetch-Site", "none"},
+			{"Sec-Fetch-User", "?1"},
+		},
+	},
+	{
+		Name: "firefox-macos",
+		Headers: []HeaderField{
+			{"User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:121.0) Gecko/20100101 Firefox/121.0"},
+			{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"},
+			{"Accept-Language", "en-US,en;q=0.5"},
+			{"Upgrade-Insecure-Requests", "1"},
+			{"Sec-Fetch-Dest", "document"},
+			{"Sec-Fetch-Mode", "navigate"},
+			{"Sec-Fetch-Site", "none"},
+			{"Sec-Fetch-User", "?1"},
+		},
+	},
+	{
+		Name: "safari-macos",
+		Headers: []HeaderField{
+			{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+			{"User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15"},
+			{"Accept-Language", "en-US,en;q=0.9"},
+		},
+	},
+}
+
+// LoadHeaderProfiles reads custom header profiles from a JSON file, in the form of:
+// [{"name": "my-browser", "headers": [{"key": "User-Agent", "value": "..."}]}]
+func LoadHeaderProfiles(fileName string) ([]*HeaderProfile, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("header profiles file open: %w", err)
+	}
+	defer file.Close()
+
+	var profiles []*HeaderProfile
+	if err := json.NewDecoder(file).Decode(&profiles); err != nil {
+		return nil, fmt.Errorf("header profiles decode: %w", err)
+	}
+	return profiles, nil
+}
+
+// HeaderRotation sets headers of a random browser profile to requests.
+// Headers already set on request are not changed.
+type HeaderRotation struct {
+	// Profiles to select from. Default: DefaultHeaderProfiles
+	Profiles []*HeaderProfile
+
+	// If true, every request of a session (Request.SessionID) uses the same profile.
+	PerSession bool
+
+	sessionProfiles sync.Map
+}
+
+func (a *HeaderRotation) ProcessRequest(r *client.Request) {
+	profiles := a.Profiles
+	if len(profiles) == 0 {
+		profiles = DefaultHeaderProfiles
+	}
+
+	profile := profiles[rand.Intn(len(profiles))]
+	if a.PerSession && r.SessionID != "" {
+		sessionProfile, _ := a.sessionProfiles.LoadOrStore(r.SessionID, profile)
+		profile = sessionProfile.(*HeaderProfile)
+	}
+
+	for _, field := range profile.Headers {
+		value := field.Value
+		if http.CanonicalHeaderKey(field.Key) == "Sec-Fetch-Site" {
+			value = fetchSite(r)
+		}
+		r.Header = client.SetDefaultHeader(r.Header, field.Key, value)
+	}
+}
+
+// fetchSite returns Sec-Fetch-Site value of request, by the page it's created from.
+// Referer middleware runs after header middlewares, so Referer header is only used if page is unknown.
+func fetchSite(r *client.Request) string {
+	u := r.URL
+	var refererURL *url.URL
+	switch referer := r.Header.Get("Referer"); {
+	case r.Referrer != nil && r.Referrer.URL != nil:
+		refererURL = r.Referrer.URL
+	case referer != "":
+		var err error
+		if refererURL, err = url.Parse(referer); err != nil {
+			return "cross-site"
+		}
+	default:
+		return "none"
+	}
+	if refererURL.Scheme == u.Scheme && refererURL.Host == u.Host {
+		return "same-origin"
+	}
+	if refererURL.Scheme == u.Scheme && registrableDomain(refererURL.Hostname()) == registrableDomain(u.Hostname()) {
+		return "same-site"
+	}
+	return "cross-site"
+}
+
+// registrableDomain returns eTLD+1 of host, or host itself if it doesn't have one, like IP addresses
+func registrableDomain(host string) string {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
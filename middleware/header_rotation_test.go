@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/geziyor/geziyor/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderRotation_ProcessRequest(t *testing.T) {
+	rotation := HeaderRotation{PerSession: true}
+
+	var userAgent string
+	for i := 0; i < 10; i++ {
+		req, err := client.NewRequest("GET", "https://example.com", nil)
+		assert.NoError(t, err)
+		req.SessionID = "alice"
+		rotation.ProcessRequest(req)
+
+		assert.NotEmpty(t, req.Header.Get("User-Agent"))
+		assert.NotEmpty(t, req.Header.Get("Accept-Language"))
+		if userAgent == "" {
+			userAgent = req.Header.Get("User-Agent")
+		}
+		assert.Equal(t, userAgent, req.Header.Get("User-Agent"))
+	}
+
+	req, err := client.NewRequest("GET", "https://example.com", nil)
+	assert.NoError(t, err)
+	req.Header.Set("User-Agent", "custom")
+	rotation.ProcessRequest(req)
+	assert.Equal(t, "custom", req.Header.Get("User-Agent"))
+}
+
+func TestHeaderRotation_FetchSite(t *testing.T) {
+	rotation := HeaderRotation{Profiles: DefaultHeaderProfiles[:1]}
+
+	for referer, site := range map[string]string{
+		"":                            "none",
+		"https://example.com/a":       "same-origin",
+		"https://www.example.com/a":   "same-site",
+		"http://example.com/a":        "cross-site",
+		"https://another.com/a":       "cross-site",
+		"https://example.github.io/a": "cross-site",
+	} {
+		req, err := client.NewRequest("GET", "https://example.com/b", nil)
+		assert.NoError(t, err)
+		if referer != "" {
+			req.Header.Set("Referer", referer)
+		}
+		rotation.ProcessRequest(req)
+		assert.Equal(t, site, req.Header.Get("Sec-Fetch-Site"), referer)
+	}
+
+	// Page that request is created from is used, before Referer middleware sets header
+	req, err := client.NewRequest("GET", "https://example.com/b", nil)
+	assert.NoError(t, err)
+	req.Referrer = &client.Referrer{URL: req.URL}
+	rotation.ProcessRequest(req)
+	assert.Equal(t, "same-origin", req.Header.Get("Sec-Fetch-Site"))
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/geziyor/geziyor/client"
+	"github.com/geziyor/geziyor/internal"
+)
+
+// HTTPError filters responses with non-2xx status codes, so that they're not passed to callbacks.
+// They're reported as *client.HTTPStatusError to ErrorProcessors and ErrorFunc instead.
+// Status codes in AllowedCodes or the request's AllowedStatusCodes are passed to callbacks.
+type HTTPError struct {
+	AllowedCodes []int
+}
+
+func (a *HTTPError) ProcessResponseV2(_ context.Context, r *client.Response) (*client.Request, error) {
+	if r.Response == nil {
+		return nil, nil
+	}
+	statusCode := r.StatusCode
+	if statusCode >= 200 && statusCode < 300 || internal.ContainsInt(a.AllowedCodes, statusCode) {
+		return nil, nil
+	}
+	if r.Request != nil && internal.ContainsInt(r.Request.AllowedStatusCodes, statusCode) {
+		return nil, nil
+	}
+	return nil, &client.HTTPStatusError{StatusCode: statusCode, Response: r}
+}
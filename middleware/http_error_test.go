@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/geziyor/geziyor/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPError_ProcessResponseV2(t *testing.T) {
+	httpError := HTTPError{AllowedCodes: []int{410}}
+
+	tests := []struct {
+		statusCode int
+		allowed    []int
+		passed     bool
+	}{
+		{200, nil, true},
+		{204, nil, true},
+		{404, nil, false},
+		{404, []int{404}, true},
+		{410, nil, true},
+		{500, []int{404}, false},
+	}
+	for _, tt := range tests {
+		req, err := client.NewRequest("GET", "https://example.com/", nil)
+		assert.NoError(t, err)
+		req.AllowedStatusCodes = tt.allowed
+		res := &client.Response{Response: &http.Response{StatusCode: tt.statusCode}, Request: req}
+
+		_, err = httpError.ProcessResponseV2(context.Background(), res)
+		if tt.passed {
+			assert.NoError(t, err)
+		} else {
+			var statusErr *client.HTTPStatusError
+			assert.True(t, errors.As(err, &statusErr))
+			assert.Equal(t, tt.statusCode, statusErr.StatusCode)
+			assert.Equal(t, res, statusErr.Response)
+		}
+	}
+}
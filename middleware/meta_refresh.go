@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geziyor/geziyor/client"
+	"golang.org/x/net/html"
+)
+
+// DefaultMetaRefreshMaxDelay is the default maximum meta refresh delay that's followed
+const DefaultMetaRefreshMaxDelay = 100 * time.Second
+
+// MetaRefresh follows <meta http-equiv="refresh"> redirects of HTML pages.
+// Redirect targets are scheduled with the original callback and Meta, instead of calling callback with the page.
+// Meta refresh redirects are counted against MaxRedirect, together with HTTP redirects.
+// Pages are tokenized to find the tag, so it works with HTML parsing disabled.
+type MetaRefresh struct {
+	// Refreshes with longer delays are not followed, as they're usually page reloads. Default: DefaultMetaRefreshMaxDelay
+	MaxDelay time.Duration
+
+	// Maximum number of redirects. Default: client.DefaultMaxRedirect
+	MaxRedirect int
+}
+
+func (a *MetaRefresh) ProcessResponseV2(_ context.Context, r *client.Response) (*client.Request, error) {
+	if r.Response == nil || r.Request == nil || r.Request.RedirectsDisabled || !r.IsHTML() {
+		return nil, nil
+	}
+
+	maxDelay := a.MaxDelay
+	if maxDelay == 0 {
+		maxDelay = DefaultMetaRefreshMaxDelay
+	}
+	maxRedirect := a.MaxRedirect
+	if maxRedirect == 0 {
+		maxRedirect = client.DefaultMaxRedirect
+	}
+
+	delay, target, ok := parseMetaRefresh(metaRefreshContent(r.Body))
+	if !ok || delay > maxDelay {
+		return nil, nil
+	}
+
+	targetURL, err := r.Request.URL.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("meta refresh url %s: %w", target, err)
+	}
+	if targetURL.String() == r.Request.URL.String() {
+		return nil, nil
+	}
+	redirectCount := r.Request.RedirectCount + len(r.Redirects)
+	if redirectCount >= maxRedirect {
+		return nil, fmt.Errorf("stopped after %d redirects", maxRedirect)
+	}
+
+	// Request is created from response, so that its Referer can be set
+	req, err := r.NewRequest("GET", targetURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("meta refresh request %s: %w", targetURL, err)
+	}
+	req.Header = r.Request.Header.Clone()
+	req.Header.Del("Cookie")
+	req.Header.Del("Referer")
+	req.Meta = r.Request.Meta
+	req.Synchronized = r.Request.Synchronized
+	req.Rendered = r.Request.Rendered
+	req.Encoding = r.Request.Encoding
+	req.MaxBodySize = r.Request.MaxBodySize
+	req.SessionID = r.Request.SessionID
+	req.AllowedStatusCodes = r.Request.AllowedStatusCodes
+	req.RedirectCount = redirectCount + 1
+	return req, nil
+}
+
+// metaRefreshContent returns content of the first refresh meta tag of page.
+// Tags in <noscript> are skipped, as tokenizer returns its content as text.
+func metaRefreshContent(body []byte) string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if string(name) != "meta" || !hasAttr {
+				continue
+			}
+			var httpEquiv, content string
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = tokenizer.TagAttr()
+				switch string(key) {
+				case "http-equiv":
+					httpEquiv = string(value)
+				case "content":
+					content = string(value)
+				}
+			}
+			if strings.EqualFold(httpEquiv, "refresh") {
+				return content
+			}
+		}
+	}
+}
+
+// parseMetaRefresh parses meta refresh content like "5; url='https://example.com'".
+// Returns false if content has no url.
+func parseMetaRefresh(content string) (time.Duration, string, bool) {
+	content = strings.TrimSpace(content)
+	i := strings.IndexAny(content, ";,")
+	if i == -1 {
+		return 0, "", false
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(content[:i]), 64)
+	if err != nil || seconds < 0 {
+		return 0, "", false
+	}
+
+	target := strings.TrimSpace(content[i+1:])
+	if len(target) >= 3 && strings.EqualFold(target[:3], "url") {
+		rest := strings.TrimSpace(target[3:])
+		if strings.HasPrefix(rest, "=") {
+			target = strings.TrimSpace(rest[1:])
+		}
+	}
+	if len(target) >= 2 && (target[0] == '\'' || target[0] == '"') {
+		if end := strings.IndexByte(target[1:], target[0]); end != -1 {
+			target = target[1 : end+1]
+		} else {
+			target = target[1:]
+		}
+	}
+	if target == "" {
+		return 0, "", false
+	}
+
+	return time.Duration(seconds * float64(time.Second)), target, true
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/geziyor/geziyor/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMetaRefresh(t *testing.T) {
+	tests := []struct {
+		content string
+		delay   time.Duration
+		target  string
+		ok      bool
+	}{
+		{"0; url=https://example.com/", 0, "https://example.com/", true},
+		{"5;URL='/next'", 5 * time.Second, "/next", true},
+		{` 1.5 , url = "/quoted" `, 1500 * time.Millisecond, "/quoted", true},
+		{"0;/bare", 0, "/bare", true},
+		{"30", 0, "", false},
+		{"abc; url=/x", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		delay, target, ok := parseMetaRefresh(tt.content)
+		assert.Equal(t, tt.ok, ok, tt.content)
+		assert.Equal(t, tt.delay, delay, tt.content)
+		assert.Equal(t, tt.target, target, tt.content)
+	}
+}
+
+func TestMetaRefresh_ProcessResponseV2(t *testing.T) {
+	response := func(html string, redirectCount int) *client.Response {
+		req, _ := client.NewRequest("GET", "https://example.com/old/page", nil)
+		req.Meta["key"] = "value"
+		req.RedirectCount = redirectCount
+		return &client.Response{
+			Response: &http.Response{Header: http.Header{"Content-Type": {"text/html"}}},
+			Request:  req,
+			Body:     []byte(html),
+		}
+	}
+	metaRefresh := MetaRefresh{MaxDelay: 10 * time.Second, MaxRedirect: 2}
+
+	req, err := metaRefresh.ProcessResponseV2(context.Background(), response(`<meta http-equiv="Refresh" content="0; url=../new">`, 0))
+	assert.NoError(t, err)
+	if assert.NotNil(t, req) {
+		assert.Equal(t, "https://example.com/new", req.URL.String())
+		assert.Equal(t, "value", req.Meta["key"])
+		assert.Equal(t, 1, req.RedirectCount)
+	}
+
+	// Request options and referrer are kept
+	res := response(`<meta http-equiv="refresh" content="0; url=/new">`, 0)
+	res.Request.Rendered = true
+	res.Request.MaxBodySize = 1024
+	req, err = metaRefresh.ProcessResponseV2(context.Background(), res)
+	assert.NoError(t, err)
+	if assert.NotNil(t, req) {
+		assert.True(t, req.Rendered)
+		assert.Equal(t, int64(1024), req.MaxBodySize)
+		if assert.NotNil(t, req.Referrer) {
+			assert.Equal(t, "https://example.com/old/page", req.Referrer.URL.String())
+		}
+	}
+
+	// Non HTML responses are ignored
+	res = response(`<meta http-equiv="refresh" content="0; url=/new">`, 0)
+	res.Header.Set("Content-Type", "text/plain")
+	req, err = metaRefresh.ProcessResponseV2(context.Background(), res)
+	assert.NoError(t, err)
+	assert.Nil(t, req)
+
+	// Long delays and noscript are ignored
+	req, err = metaRefresh.ProcessResponseV2(context.Background(), response(`<meta http-equiv="refresh" content="60; url=/new">`, 0))
+	assert.NoError(t, err)
+	assert.Nil(t, req)
+	req, err = metaRefresh.ProcessResponseV2(context.Background(), response(`<noscript><meta http-equiv="refresh" content="0; url=/new"></noscript>`, 0))
+	assert.NoError(t, err)
+	assert.Nil(t, req)
+
+	// Max redirect, counting HTTP redirects of response
+	req, err = metaRefresh.ProcessResponseV2(context.Background(), response(`<meta http-equiv="refresh" content="0; url=/new">`, 2))
+	assert.Error(t, err)
+	assert.Nil(t, req)
+	res = response(`<meta http-equiv="refresh" content="0; url=/new">`, 1)
+	res.Redirects = []client.Redirect{{StatusCode: 302}}
+	req, err = metaRefresh.ProcessResponseV2(context.Background(), res)
+	assert.Error(t, err)
+	assert.Nil(t, req)
+}
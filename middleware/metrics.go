@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"github.com/geziyor/geziyor/client"
 	"github.com/geziyor/geziyor/metrics"
 	"strconv"
@@ -17,4 +19,15 @@ func (a *Metrics) ProcessRequest(r *client.Request) {
 
 func (a *Metrics) ProcessResponse(r *client.Response) {
 	a.Metrics.ResponseCounter.With("status", strconv.Itoa(r.StatusCode)).Add(1)
+	if r.Truncated {
+		a.Metrics.TruncatedResponseCounter.With("action", "truncated").Add(1)
+	}
+}
+
+func (a *Metrics) ProcessError(_ context.Context, _ *client.Request, err error) error {
+	var bodyTooLargeErr *client.BodyTooLargeError
+	if errors.As(err, &bodyTooLargeErr) {
+		a.Metrics.TruncatedResponseCounter.With("action", "aborted").Add(1)
+	}
+	return err
 }
@@ -1,9 +1,91 @@
 package middleware
 
 import (
+	"context"
+	"reflect"
+	"sort"
+
 	"github.com/geziyor/geziyor/client"
 )
 
+// Built-in middleware names. Can be used to replace, disable or reorder built-in middlewares.
+const (
+	AllowedDomainsName    = "AllowedDomains"
+	DuplicateRequestsName = "DuplicateRequests"
+	HeaderRotationName    = "HeaderRotation"
+	HeadersName           = "Headers"
+	DelayName             = "Delay"
+	MetricsName           = "Metrics"
+	RobotsTxtName         = "RobotsTxt"
+	RefererName           = "Referer"
+	ParseHTMLName         = "ParseHTML"
+	LogStatsName          = "LogStats"
+	HTTPErrorName         = "HTTPError"
+	MetaRefreshName       = "MetaRefresh"
+)
+
+// DefaultOrder is the order of custom middlewares that are not given an order. They run after built-ins.
+// Built-in orders are:
+// Request: AllowedDomains 100, HeaderRotation 300, Headers 400, DuplicateRequests 450, Delay 500, Metrics 600, RobotsTxt 700, Referer 800
+// Response: ParseHTML 100, LogStats 200, Metrics 600, MetaRefresh 850, HTTPError 900
+const DefaultOrder = 1000
+
+// Middleware is a named middleware with an order weight. Middlewares with lower order run first.
+type Middleware struct {
+	// Name is used to replace a built-in middleware with the same name. Can be left empty for custom middlewares.
+	Name string
+
+	// Order weight of middleware. Middlewares with the same order run in the order they're given.
+	// Zero means DefaultOrder for custom middlewares of Options.Middlewares. Use negative orders to run before all built-ins.
+	Order int
+
+	// Processor should implement one or more of RequestProcessor, ResponseProcessor,
+	// RequestProcessorV2, ResponseProcessorV2 and ErrorProcessor. It's used for all of them.
+	Processor interface{}
+}
+
+// Chains sorts middlewares by order and splits them into request, response and error processor chains.
+// A processor is added to every chain whose interface it implements, only once.
+func Chains(middlewares []Middleware) ([]RequestProcessorV2, []ResponseProcessorV2, []ErrorProcessor) {
+	sorted := make([]Middleware, len(middlewares))
+	copy(sorted, middlewares)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+
+	var reqProcessors []RequestProcessorV2
+	var resProcessors []ResponseProcessorV2
+	var errProcessors []ErrorProcessor
+	seen := make(map[interface{}]struct{})
+	for _, m := range sorted {
+		if m.Processor == nil {
+			continue
+		}
+		if reflect.TypeOf(m.Processor).Comparable() {
+			if _, exists := seen[m.Processor]; exists {
+				continue
+			}
+			seen[m.Processor] = struct{}{}
+		}
+		switch p := m.Processor.(type) {
+		case RequestProcessorV2:
+			reqProcessors = append(reqProcessors, p)
+		case RequestProcessor:
+			reqProcessors = append(reqProcessors, AdaptRequestProcessor(p))
+		}
+		switch p := m.Processor.(type) {
+		case ResponseProcessorV2:
+			resProcessors = append(resProcessors, p)
+		case ResponseProcessor:
+			resProcessors = append(resProcessors, AdaptResponseProcessor(p))
+		}
+		if p, ok := m.Processor.(ErrorProcessor); ok {
+			errProcessors = append(errProcessors, p)
+		}
+	}
+	return reqProcessors, resProcessors, errProcessors
+}
+
 // RequestResponseProcessor interface is for middlewares that needs to process both requests and responses
 type RequestResponseProcessor interface {
 	RequestProcessor
@@ -11,7 +93,7 @@ type RequestResponseProcessor interface {
 }
 
 // RequestProcessor called before requests made.
-// Set request.Cancelled = true to cancel request
+// Set request.Cancelled = true or use request.CancelWithReason to cancel request
 type RequestProcessor interface {
 	ProcessRequest(r *client.Request)
 }
@@ -20,3 +102,61 @@ type RequestProcessor interface {
 type ResponseProcessor interface {
 	ProcessResponse(r *client.Response)
 }
+
+// RequestProcessorV2 called before requests made.
+// Returning a non-nil response skips the remaining request processors and the download,
+// and the response is processed as if it's downloaded. Returned response should have its http.Response set.
+// Returning an error stops the request and reports error to ErrorProcessors and ErrorFunc.
+// Set request.Cancelled = true or use request.CancelWithReason to cancel request.
+// Cancelled requests are reported as *client.CancelledError if Options.CancelledRequestsReported is set.
+type RequestProcessorV2 interface {
+	ProcessRequestV2(ctx context.Context, r *client.Request) (*client.Response, error)
+}
+
+// ResponseProcessorV2 called after request response receive.
+// Returning a non-nil request schedules it with the same callback, instead of calling the callback with this response.
+// Returning an error reports it to ErrorProcessors and ErrorFunc and skips the callback.
+type ResponseProcessorV2 interface {
+	ProcessResponseV2(ctx context.Context, r *client.Response) (*client.Request, error)
+}
+
+// ErrorProcessor called when request processing, downloading or response processing fails.
+// Returning nil handles the error, so that it's not passed to the next ErrorProcessors and ErrorFunc.
+// Otherwise, returned error (which can be the same or a wrapped one) is passed on.
+type ErrorProcessor interface {
+	ProcessError(ctx context.Context, r *client.Request, err error) error
+}
+
+// AdaptRequestProcessor converts RequestProcessor to RequestProcessorV2
+func AdaptRequestProcessor(p RequestProcessor) RequestProcessorV2 {
+	if v2, ok := p.(RequestProcessorV2); ok {
+		return v2
+	}
+	return &requestProcessorAdapter{p}
+}
+
+// AdaptResponseProcessor converts ResponseProcessor to ResponseProcessorV2
+func AdaptResponseProcessor(p ResponseProcessor) ResponseProcessorV2 {
+	if v2, ok := p.(ResponseProcessorV2); ok {
+		return v2
+	}
+	return &responseProcessorAdapter{p}
+}
+
+type requestProcessorAdapter struct {
+	RequestProcessor
+}
+
+func (a *requestProcessorAdapter) ProcessRequestV2(_ context.Context, r *client.Request) (*client.Response, error) {
+	a.ProcessRequest(r)
+	return nil, nil
+}
+
+type responseProcessorAdapter struct {
+	ResponseProcessor
+}
+
+func (a *responseProcessorAdapter) ProcessResponseV2(_ context.Context, r *client.Response) (*client.Request, error) {
+	a.ProcessResponse(r)
+	return nil, nil
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/geziyor/geziyor/client"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMiddleware struct {
+	name    string
+	records *[]string
+}
+
+func (m *recordingMiddleware) ProcessRequest(r *client.Request) {
+	*m.records = append(*m.records, m.name)
+}
+
+func TestChains(t *testing.T) {
+	var records []string
+	reqProcessors, resProcessors, errProcessors := Chains([]Middleware{
+		{Order: 300, Processor: &recordingMiddleware{"third", &records}},
+		{Order: 100, Processor: &recordingMiddleware{"first", &records}},
+		{Order: 200, Processor: &recordingMiddleware{"second-a", &records}},
+		{Order: 200, Processor: &recordingMiddleware{"second-b", &records}},
+		{Order: 100, Processor: &LogStats{LogDisabled: true}},
+	})
+	assert.Len(t, reqProcessors, 4)
+	assert.Len(t, resProcessors, 1)
+	assert.Len(t, errProcessors, 0)
+
+	req, _ := client.NewRequest("GET", "https://example.com", nil)
+	for _, p := range reqProcessors {
+		_, err := p.ProcessRequestV2(context.Background(), req)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"first", "second-a", "second-b", "third"}, records)
+}
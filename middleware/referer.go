@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/geziyor/geziyor/client"
+)
+
+// ReferrerPolicy is the policy that decides Referer header of requests
+// See https://www.w3.org/TR/referrer-policy/#referrer-policies
+type ReferrerPolicy string
+
+// Referrer policies
+const (
+	NoReferrer                  ReferrerPolicy = "no-referrer"
+	NoReferrerWhenDowngrade     ReferrerPolicy = "no-referrer-when-downgrade"
+	SameOrigin                  ReferrerPolicy = "same-origin"
+	Origin                      ReferrerPolicy = "origin"
+	StrictOrigin                ReferrerPolicy = "strict-origin"
+	OriginWhenCrossOrigin       ReferrerPolicy = "origin-when-cross-origin"
+	StrictOriginWhenCrossOrigin ReferrerPolicy = "strict-origin-when-cross-origin"
+	UnsafeURL                   ReferrerPolicy = "unsafe-url"
+)
+
+// Referer sets Referer header of requests created from responses, like followed links,
+// honoring the page's Referrer-Policy header or referrer meta tag.
+// Requests should be created with Response.NewRequest or Geziyor.Follow, which set their Request.Referrer.
+type Referer struct {
+	// DefaultPolicy is used if page has no referrer policy. Default: StrictOriginWhenCrossOrigin
+	DefaultPolicy ReferrerPolicy
+}
+
+func (a *Referer) ProcessRequest(r *client.Request) {
+	if r.Referrer == nil || r.Referrer.URL == nil || r.Header.Get("Referer") != "" {
+		return
+	}
+	policy, ok := parseReferrerPolicy(r.Referrer.Policy)
+	if !ok {
+		policy = a.DefaultPolicy
+	}
+	if referer := ReferrerFor(policy, r.Referrer.URL, r.URL); referer != "" {
+		r.Header.Set("Referer", referer)
+	}
+}
+
+// ReferrerFor returns the Referer header value of a request from page to target, according to policy.
+// Returns empty string if Referer shouldn't be sent.
+func ReferrerFor(policy ReferrerPolicy, page *url.URL, target *url.URL) string {
+	if page.Scheme != "http" && page.Scheme != "https" {
+		return ""
+	}
+	full := &url.URL{Scheme: page.Scheme, Host: page.Host, Path: page.Path, RawPath: page.RawPath, RawQuery: page.RawQuery}
+	if full.Path == "" {
+		full.Path = "/"
+	}
+	origin := page.Scheme + "://" + page.Host + "/"
+	sameOrigin := page.Scheme == target.Scheme && page.Host == target.Host
+	downgrade := page.Scheme == "https" && target.Scheme != "https"
+
+	switch policy {
+	case NoReferrer:
+		return ""
+	case NoReferrerWhenDowngrade:
+		if downgrade {
+			return ""
+		}
+		return full.String()
+	case SameOrigin:
+		if !sameOrigin {
+			return ""
+		}
+		return full.String()
+	case Origin:
+		return origin
+	case StrictOrigin:
+		if downgrade {
+			return ""
+		}
+		return origin
+	case OriginWhenCrossOrigin:
+		if !sameOrigin {
+			return origin
+		}
+		return full.String()
+	case UnsafeURL:
+		return full.String()
+	default: // StrictOriginWhenCrossOrigin
+		if sameOrigin {
+			return full.String()
+		}
+		if downgrade {
+			return ""
+		}
+		return origin
+	}
+}
+
+// parseReferrerPolicy returns the last valid policy in comma separated policy list
+func parseReferrerPolicy(value string) (ReferrerPolicy, bool) {
+	var policy ReferrerPolicy
+	for _, token := range strings.Split(value, ",") {
+		switch p := ReferrerPolicy(strings.ToLower(strings.TrimSpace(token))); p {
+		case NoReferrer, NoReferrerWhenDowngrade, SameOrigin, Origin, StrictOrigin,
+			OriginWhenCrossOrigin, StrictOriginWhenCrossOrigin, UnsafeURL:
+			policy = p
+		// Legacy meta values
+		case "never":
+			policy = NoReferrer
+		case "always":
+			policy = UnsafeURL
+		case "default":
+			policy = StrictOriginWhenCrossOrigin
+		}
+	}
+	return policy, policy != ""
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/geziyor/geziyor/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferrerFor(t *testing.T) {
+	page, _ := url.Parse("https://example.com/page?q=1#top")
+	sameOrigin, _ := url.Parse("https://example.com/other")
+	crossOrigin, _ := url.Parse("https://other.com/")
+	downgrade, _ := url.Parse("http://example.com/")
+
+	tests := []struct {
+		policy ReferrerPolicy
+		target *url.URL
+		want   string
+	}{
+		{NoReferrer, sameOrigin, ""},
+		{UnsafeURL, downgrade, "https://example.com/page?q=1"},
+		{NoReferrerWhenDowngrade, downgrade, ""},
+		{SameOrigin, crossOrigin, ""},
+		{SameOrigin, sameOrigin, "https://example.com/page?q=1"},
+		{Origin, sameOrigin, "https://example.com/"},
+		{StrictOrigin, downgrade, ""},
+		{OriginWhenCrossOrigin, crossOrigin, "https://example.com/"},
+		{StrictOriginWhenCrossOrigin, sameOrigin, "https://example.com/page?q=1"},
+		{StrictOriginWhenCrossOrigin, crossOrigin, "https://example.com/"},
+		{StrictOriginWhenCrossOrigin, downgrade, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ReferrerFor(tt.policy, page, tt.target), tt.policy)
+	}
+}
+
+func TestReferer(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><head><meta name="referrer" content="origin"></head></html>`))
+	assert.NoError(t, err)
+	req, _ := client.NewRequest("GET", "https://example.com/list", nil)
+	res := &client.Response{Response: &http.Response{Header: http.Header{}}, HTMLDoc: doc, Request: req}
+
+	referer := Referer{}
+	next, _ := res.NewRequest("GET", "/next#section", nil)
+	referer.ProcessRequest(next)
+	assert.Equal(t, "https://example.com/", next.Header.Get("Referer"))
+
+	// Requests not created from responses have no Referer
+	other, _ := client.NewRequest("GET", "https://example.com/other", nil)
+	referer.ProcessRequest(other)
+	assert.Equal(t, "", other.Header.Get("Referer"))
+
+	// Referrer-Policy header overrides meta tag
+	res.Header.Set("Referrer-Policy", "unsafe-url")
+	next, _ = res.NewRequest("GET", "/next", nil)
+	referer.ProcessRequest(next)
+	assert.Equal(t, "https://example.com/list", next.Header.Get("Referer"))
+
+	// Default policy is used if page has no policy
+	res = &client.Response{Response: &http.Response{Header: http.Header{}}, Request: req}
+	referer.DefaultPolicy = NoReferrer
+	next, _ = res.NewRequest("GET", "/next", nil)
+	referer.ProcessRequest(next)
+	assert.Equal(t, "", next.Header.Get("Referer"))
+}
@@ -63,6 +63,6 @@ func (m *RobotsTxt) ProcessRequest(r *client.Request) {
 	if !robotsData.TestAgent(r.URL.Path, r.UserAgent()) {
 		m.metrics.RobotsTxtForbiddenCounter.With("method", r.Method).Add(1)
 		internal.Logger.Println("Forbidden by robots.txt:", r.URL.String())
-		r.Cancel()
+		r.CancelWithReason(&client.RobotsForbiddenError{URL: r.URL.String()})
 	}
 }
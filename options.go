@@ -4,6 +4,7 @@ import (
 	"github.com/chromedp/chromedp"
 	"github.com/geziyor/geziyor/cache"
 	"github.com/geziyor/geziyor/client"
+	"github.com/geziyor/geziyor/dupefilter"
 	"github.com/geziyor/geziyor/export"
 	"github.com/geziyor/geziyor/metrics"
 	"github.com/geziyor/geziyor/middleware"
@@ -16,6 +17,8 @@ import (
 type Options struct {
 	// AllowedDomains is domains that are allowed to make requests
 	// If empty, any domain is allowed
+	// Subdomains are allowed too, and ports are ignored. Wildcards ("*.example.com")
+	// and regular expressions ("regexp:^api[0-9]+\.example\.com$") are supported.
 	AllowedDomains []string
 
 	// Chrome headless browser WS endpoint.
@@ -44,28 +47,96 @@ type Options struct {
 	// Subdomains are different than top domain
 	ConcurrentRequestsPerDomain int
 
+	// CookieJar is used instead of the default in-memory cookie jar.
+	// Use jar.New to keep cookies between runs.
+	CookieJar http.CookieJar
+
 	// If set true, cookies won't send.
 	CookiesDisabled bool
 
+	// CookiesFile is a Netscape cookies.txt file to seed cookie jar with, before scraping started.
+	CookiesFile string
+
 	// ErrorFunc is callback of errors.
 	// If not defined, all errors will be logged.
+	// Use errors.As with client error types like *client.DNSError, *client.TLSError or *client.RetriesExhaustedError to handle them.
 	ErrorFunc func(g *Geziyor, r *client.Request, err error)
 
+	// Called when request processing, downloading or response processing fails, before ErrorFunc
+	ErrorMiddlewares []middleware.ErrorProcessor
+
+	// If true, requests cancelled by middlewares (duplicates, robots.txt, not allowed domains etc.)
+	// are reported to ErrorFunc as *client.CancelledError, instead of silently dropped.
+	CancelledRequestsReported bool
+
+	// If true, responses with non-2xx status codes (after retries) are not passed to callbacks,
+	// they're passed to ErrorFunc as *client.HTTPStatusError.
+	// Use HTTPErrorAllowedCodes or Request.AllowedStatusCodes to still handle some status codes in callbacks.
+	HTTPErrorEnabled bool
+
+	// Non-2xx status codes that are passed to callbacks when HTTPErrorEnabled
+	HTTPErrorAllowedCodes []int
+
 	// For extracting data
 	Exporters []export.Exporter
 
+	// Exporters that export items one by one, so that export errors are reported to ExportErrorFunc while scraping
+	ExportersV2 []export.ExporterV2
+
+	// ExportErrorFunc is callback of exporter errors. item is nil for errors of opening and closing exporters.
+	// It's called from exporter goroutines, so exporting is paused while it's running, which can be used for backoff.
+	// Call g.Stop to stop scraping. If not defined, errors are logged.
+	ExportErrorFunc func(g *Geziyor, item interface{}, err error)
+
+	// Buffer options of exporters, so that a slow exporter doesn't block others and scraping.
+	// Wrap exporters with export.Buffered to set options of an exporter.
+	ExportBuffer export.BufferOptions
+
+	// HeaderProfiles are browser header profiles (User-Agent, Accept, sec-ch-ua etc.) to rotate on requests.
+	// Profile headers take precedence over UserAgent option.
+	// Use middleware.DefaultHeaderProfiles for built-in ones, or middleware.LoadHeaderProfiles for custom ones.
+	HeaderProfiles []*middleware.HeaderProfile
+
+	// If true, every request of a session uses the same header profile.
+	HeaderProfilesPerSession bool
+
+	// DeniedDomains is domains that are not allowed to make requests, even if they're in AllowedDomains.
+	// Supports the same patterns as AllowedDomains.
+	DeniedDomains []string
+
+	// DisabledMiddlewares are the names of built-in middlewares to disable. See middleware.AllowedDomainsName etc.
+	DisabledMiddlewares []string
+
 	// Disable logging by setting this true
 	LogDisabled bool
 
-	// Max body reading size in bytes. Default: 1GB
+	// Max body reading size in bytes. Can be overridden per request with Request.MaxBodySize. Default: 1GB
 	MaxBodySize int64
 
-	// Maximum redirection time. Default: 10
+	// Action taken when response body exceeds max body size: Truncate (default), Warn or Abort.
+	// Truncated responses have Response.Truncated set.
+	MaxBodyAction client.MaxBodyAction
+
+	// Maximum redirection time. Meta refresh redirects are also counted. Default: 10
 	MaxRedirect int
 
+	// If true, <meta http-equiv="refresh"> redirects are followed with the same callback and Meta
+	MetaRefreshEnabled bool
+
+	// Meta refresh redirects with longer delays are not followed. Default: 100 seconds
+	MetaRefreshMaxDelay time.Duration
+
 	// Scraper metrics exporting type. See metrics.Type
 	MetricsType metrics.Type
 
+	// MiddlewareOrders overrides order weights of built-in middlewares by name. See middleware.DefaultOrder
+	MiddlewareOrders map[string]int
+
+	// Middlewares are custom middlewares with order weights. Lower weights run first,
+	// so they can be run before built-ins. Zero order means middleware.DefaultOrder, after built-ins.
+	// A middleware with a built-in's name replaces the built-in, keeping its order unless given.
+	Middlewares []middleware.Middleware
+
 	// ParseFunc is callback of StartURLs response.
 	ParseFunc func(g *Geziyor, r *client.Response)
 
@@ -75,11 +146,24 @@ type Options struct {
 	// ProxyFunc setting proxy for each request
 	ProxyFunc func(*http.Request) (*url.URL, error)
 
+	// ProxyPool selects proxies while tracking their health, benching dead or banned ones.
+	// Overrides ProxyFunc if set. See client.NewProxyPool
+	ProxyPool *client.ProxyPool
+
 	// Rendered requests pre actions. Setting this will override the existing default.
 	// And you'll need to handle all rendered actions, like navigation, waiting, response etc.
 	// If you need to make custom actions in addition to the defaults, use Request.Actions instead of this.
 	PreActions []chromedp.Action
 
+	// If true, Referer header of requests created from responses is set to the page, honoring page's referrer policy.
+	// Only requests created with Geziyor.Follow or Response.NewRequest get a Referer header;
+	// requests of Geziyor.Get and other helpers don't, even if they're made in parse callbacks.
+	RefererEnabled bool
+
+	// RefererPolicy is the referrer policy used if page has no policy.
+	// Default: middleware.StrictOriginWhenCrossOrigin
+	RefererPolicy middleware.ReferrerPolicy
+
 	// Request delays
 	RequestDelay time.Duration
 
@@ -89,9 +173,17 @@ type Options struct {
 	// Called before requests made to manipulate requests
 	RequestMiddlewares []middleware.RequestProcessor
 
+	// Called before requests made, after RequestMiddlewares.
+	// Can return responses or errors without making requests
+	RequestMiddlewaresV2 []middleware.RequestProcessorV2
+
 	// Called after response received
 	ResponseMiddlewares []middleware.ResponseProcessor
 
+	// Called after response received, after ResponseMiddlewares.
+	// Can return new requests to be made instead of calling the callback, or errors
+	ResponseMiddlewaresV2 []middleware.ResponseProcessorV2
+
 	// RequestsPerSecond limits requests that is made per seconds. Default: No limit
 	RequestsPerSecond float64
 
@@ -120,6 +212,17 @@ type Options struct {
 	// Revisiting same URLs is disabled by default
 	URLRevisitEnabled bool
 
+	// RequestFingerprinter identifies requests for both duplicate filtering and cache keys.
+	// If not set, client.DefaultFingerprinter is used for duplicate filtering and URLs are used as cache keys.
+	// Headers of fingerprints are set by the time DuplicateRequests runs, except Referer.
+	RequestFingerprinter client.RequestFingerprinter
+
+	// DupeFilter stores visited request fingerprints.
+	// - dupefilter.NewMemory(): Exact, memory grows with requests (default)
+	// - dupefilter.NewBloom(): Bounded memory with a configurable false positive rate
+	// - leveldbfilter.New(): Persistent on disk, survives restarts
+	DupeFilter dupefilter.Filter
+
 	// User Agent.
 	// Default: "Geziyor 1.0"
 	UserAgent string